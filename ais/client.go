@@ -0,0 +1,329 @@
+package ais
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/config"
+	"golang.org/x/sync/singleflight"
+)
+
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+var ErrTokenNotFound = errors.New("ais: token not found")
+
+// Error 授权服务返回的 OAuth2 错误
+type Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("ais: %s (%d): %s", e.Code, e.StatusCode, e.Description)
+	}
+	return fmt.Sprintf("ais: %s (%d)", e.Code, e.StatusCode)
+}
+
+// Token 授权服务签发的 token
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IdToken      string    `json:"id_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// Valid access token 存在且未过期，expiry 为空视为不过期
+func (t *Token) Valid(skew time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(skew).Before(t.Expiry)
+}
+
+// Identity verify_uri 返回的身份信息
+type Identity struct {
+	Active    bool                   `json:"active"`
+	Subject   string                 `json:"sub"`
+	ClientId  string                 `json:"client_id"`
+	Scope     string                 `json:"scope"`
+	ExpiresAt int64                  `json:"exp"`
+	Raw       map[string]interface{} `json:"-"`
+}
+
+type Options struct {
+	HTTPClient *http.Client
+	Store      TokenStore
+	// ExpirySkew token 在过期前多久视为失效并刷新
+	ExpirySkew time.Duration
+	// VerifyCacheTTL 校验结果缓存时间，不超过 token 自身的过期时间
+	VerifyCacheTTL time.Duration
+	// VerifyCacheSize 校验结果最多缓存的条目数，默认 DefaultMemoryStoreSize
+	VerifyCacheSize int
+}
+
+type Option func(*Options)
+
+func SetHTTPClient(cli *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = cli
+	}
+}
+
+func SetStore(store TokenStore) Option {
+	return func(o *Options) {
+		o.Store = store
+	}
+}
+
+func SetExpirySkew(skew time.Duration) Option {
+	return func(o *Options) {
+		o.ExpirySkew = skew
+	}
+}
+
+func SetVerifyCacheTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.VerifyCacheTTL = ttl
+	}
+}
+
+func SetVerifyCacheSize(size int) Option {
+	return func(o *Options) {
+		o.VerifyCacheSize = size
+	}
+}
+
+// Client 授权码模式客户端
+type Client struct {
+	conf    *config.AisConf
+	options Options
+	group   singleflight.Group
+	verify  *memoryStore
+}
+
+// New 创建客户端
+func New(conf *config.AisConf, opts ...Option) *Client {
+	options := Options{
+		ExpirySkew:     30 * time.Second,
+		VerifyCacheTTL: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if options.Store == nil {
+		options.Store = NewMemoryStore()
+	}
+	return &Client{conf: conf, options: options, verify: newMemoryStore(options.VerifyCacheSize)}
+}
+
+// NewFromConfig 使用 ais 配置与全局 http client 创建客户端
+func NewFromConfig(opts ...Option) (*Client, error) {
+	conf, err := config.GetParser().GetAisConf()
+	if err != nil {
+		return nil, err
+	}
+	if httpCli := config.GetParser().GetHTTPClient(); httpCli != nil && httpCli.GetClient() != nil {
+		opts = append([]Option{SetHTTPClient(httpCli.GetClient())}, opts...)
+	}
+	return New(conf, opts...), nil
+}
+
+// AuthCodeURL 登录跳转地址
+func (c *Client) AuthCodeURL(state string) string {
+	responseType := c.conf.ResponseType
+	if responseType == "" {
+		responseType = "code"
+	}
+	query := url.Values{}
+	query.Set("client_id", c.conf.ClientId)
+	query.Set("response_type", responseType)
+	query.Set("redirect_uri", c.conf.RedirectUri)
+	if c.conf.Scope != "" {
+		query.Set("scope", c.conf.Scope)
+	}
+	query.Set("state", state)
+
+	sep := "?"
+	if strings.Contains(c.conf.CodeUri, "?") {
+		sep = "&"
+	}
+	return c.conf.CodeUri + sep + query.Encode()
+}
+
+// Exchange 使用授权码换取 token
+func (c *Client) Exchange(ctx context.Context, code string) (*Token, error) {
+	grantType := c.conf.GrantType
+	if grantType == "" {
+		grantType = "authorization_code"
+	}
+	form := url.Values{}
+	form.Set("grant_type", grantType)
+	form.Set("code", code)
+	form.Set("redirect_uri", c.conf.RedirectUri)
+	return c.requestToken(ctx, form)
+}
+
+// Refresh 使用 refresh token 换取新 token
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	token, err := c.requestToken(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	// 授权服务未轮换 refresh token 时沿用旧值
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// Save 缓存 token，key 通常为用户或会话 id
+func (c *Client) Save(ctx context.Context, key string, token *Token) error {
+	return c.options.Store.Set(ctx, key, token)
+}
+
+// Token 读取缓存的 token，临近过期时自动刷新，同一个 key 的并发刷新只请求一次
+func (c *Client) Token(ctx context.Context, key string) (*Token, error) {
+	token, err := c.options.Store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if token.Valid(c.options.ExpirySkew) {
+		return token, nil
+	}
+	if token.RefreshToken == "" {
+		_ = c.options.Store.Delete(ctx, key)
+		return nil, ErrTokenNotFound
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		refreshed, err := c.Refresh(ctx, token.RefreshToken)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.options.Store.Set(ctx, key, refreshed); err != nil {
+			return nil, err
+		}
+		return refreshed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Token), nil
+}
+
+// Verify 调用 verify_uri 校验 access token，结果按 VerifyCacheTTL 缓存
+func (c *Client) Verify(ctx context.Context, accessToken string) (*Identity, error) {
+	sum := sha256.Sum256([]byte(accessToken))
+	cacheKey := hex.EncodeToString(sum[:])
+	if cached, ok := c.verify.load(cacheKey); ok {
+		return cached.(*Identity), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.conf.VerifyUri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	identity := &Identity{}
+	if err := json.Unmarshal(body, identity); err != nil {
+		return nil, fmt.Errorf("ais: decode verify response: %w", err)
+	}
+	if err := json.Unmarshal(body, &identity.Raw); err != nil {
+		return nil, fmt.Errorf("ais: decode verify response: %w", err)
+	}
+	if _, ok := identity.Raw["active"]; !ok {
+		identity.Active = true
+	}
+	if !identity.Active {
+		return nil, &Error{StatusCode: http.StatusUnauthorized, Code: "invalid_token"}
+	}
+
+	ttl := c.options.VerifyCacheTTL
+	if identity.ExpiresAt > 0 {
+		if remain := time.Until(time.Unix(identity.ExpiresAt, 0)); remain < ttl {
+			ttl = remain
+		}
+	}
+	if ttl > 0 {
+		c.verify.store(cacheKey, identity, ttl)
+	}
+	return identity, nil
+}
+
+func (c *Client) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.conf.ClientId)
+	if c.conf.ClientAssertion != "" {
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", c.conf.ClientAssertion)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.conf.TokenUri, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, fmt.Errorf("ais: decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("ais: token response missing access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	res, err := c.options.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ais: request %s: %w", req.URL.Path, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("ais: read response: %w", err)
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		oauthErr := &Error{StatusCode: res.StatusCode}
+		if json.Unmarshal(body, oauthErr) != nil || oauthErr.Code == "" {
+			oauthErr.Code = http.StatusText(res.StatusCode)
+		}
+		return nil, oauthErr
+	}
+	return body, nil
+}
@@ -0,0 +1,199 @@
+package ais
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+type fakeServer struct {
+	*httptest.Server
+	refreshCalls int32
+	verifyCalls  int32
+}
+
+// newFakeServer 模拟授权服务：/token 支持授权码与刷新，/verify 校验 access token
+func newFakeServer(t *testing.T) *fakeServer {
+	fs := &fakeServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("client_id") != "cid" || r.Form.Get("client_assertion") != "assertion" ||
+			r.Form.Get("client_assertion_type") != clientAssertionType {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "good-code" || r.Form.Get("redirect_uri") != "https://app/callback" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"bad code"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at-1","token_type":"Bearer","refresh_token":"rt-1","expires_in":1}`))
+		case "refresh_token":
+			atomic.AddInt32(&fs.refreshCalls, 1)
+			time.Sleep(20 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"access_token":"at-2","token_type":"Bearer","expires_in":3600}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	mux.HandleFunc("/verify", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fs.verifyCalls, 1)
+		if r.Header.Get("Authorization") != "Bearer at-2" {
+			_, _ = w.Write([]byte(`{"active":false}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "sub": "u1", "email": "u1@example.com"})
+	})
+	fs.Server = httptest.NewServer(mux)
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func newTestClient(fs *fakeServer) *Client {
+	return New(&config.AisConf{
+		ClientId:        "cid",
+		RedirectUri:     "https://app/callback",
+		Scope:           "openid profile",
+		CodeUri:         fs.URL + "/authorize",
+		TokenUri:        fs.URL + "/token",
+		VerifyUri:       fs.URL + "/verify",
+		ClientAssertion: "assertion",
+	}, SetExpirySkew(0))
+}
+
+func TestExchangeRefreshVerify(t *testing.T) {
+	fs := newFakeServer(t)
+	client := newTestClient(fs)
+	ctx := context.Background()
+
+	if _, err := client.Exchange(ctx, "bad-code"); err == nil || err.(*Error).Code != "invalid_grant" {
+		t.Fatalf("bad code should fail with invalid_grant: %v", err)
+	}
+
+	token, err := client.Exchange(ctx, "good-code")
+	if err != nil || token.AccessToken != "at-1" || token.Expiry.IsZero() {
+		t.Fatalf("exchange: %v %+v", err, token)
+	}
+	_ = client.Save(ctx, "session-1", token)
+
+	// access token 过期后，并发读取只触发一次刷新
+	time.Sleep(1100 * time.Millisecond)
+	done := make(chan *Token, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			tk, err := client.Token(ctx, "session-1")
+			if err != nil {
+				t.Error(err)
+			}
+			done <- tk
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if tk := <-done; tk == nil || tk.AccessToken != "at-2" || tk.RefreshToken != "rt-1" {
+			t.Fatalf("refreshed token: %+v", tk)
+		}
+	}
+	if n := atomic.LoadInt32(&fs.refreshCalls); n != 1 {
+		t.Fatalf("refresh calls %d", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		identity, err := client.Verify(ctx, "at-2")
+		if err != nil || identity.Subject != "u1" || identity.Raw["email"] != "u1@example.com" {
+			t.Fatalf("verify: %v %+v", err, identity)
+		}
+	}
+	if n := atomic.LoadInt32(&fs.verifyCalls); n != 1 {
+		t.Fatalf("verify should be cached, calls %d", n)
+	}
+	if _, err := client.Verify(ctx, "at-1"); err == nil {
+		t.Fatal("inactive token should fail")
+	}
+}
+
+func TestLoginCallbackHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fs := newFakeServer(t)
+	client := newTestClient(fs)
+
+	r := gin.New()
+	r.GET("/login", LoginHandler(client))
+	r.GET("/callback", CallbackHandler(client, func(c *gin.Context, token *Token) {
+		c.String(http.StatusOK, token.AccessToken)
+	}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status %d", w.Code)
+	}
+	location, _ := url.Parse(w.Header().Get("Location"))
+	state := location.Query().Get("state")
+	if !strings.HasPrefix(location.String(), fs.URL+"/authorize?") || state == "" ||
+		location.Query().Get("client_id") != "cid" || location.Query().Get("response_type") != "code" {
+		t.Fatalf("unexpected redirect: %s", location)
+	}
+	cookie := w.Result().Cookies()[0]
+
+	// state 不一致时拒绝
+	req := httptest.NewRequest(http.MethodGet, "/callback?code=good-code&state=forged", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("forged state status %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/callback?code=good-code&state="+url.QueryEscape(state), nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "at-1" {
+		t.Fatalf("callback: %d %s", w.Code, w.Body.String())
+	}
+
+	// 不允许把含 refresh token 的 token 直接返回给浏览器
+	defer func() {
+		if recover() == nil {
+			t.Fatal("CallbackHandler without onLogin should panic")
+		}
+	}()
+	CallbackHandler(client, nil)
+}
+
+func TestMemoryStoreBounded(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore(2)
+
+	// 带 refresh token 的 token 也有过期时间
+	if ttl := storeTTL(&Token{AccessToken: "a", RefreshToken: "r"}); ttl != RefreshTokenTTL {
+		t.Fatalf("refresh token ttl %s", ttl)
+	}
+
+	_ = store.Set(ctx, "never", &Token{AccessToken: "a"})
+	_ = store.Set(ctx, "soon", &Token{AccessToken: "b", Expiry: time.Now().Add(time.Minute)})
+	_ = store.Set(ctx, "later", &Token{AccessToken: "c", Expiry: time.Now().Add(time.Hour)})
+	if len(store.items) != 2 {
+		t.Fatalf("store size %d", len(store.items))
+	}
+	// 容量已满时淘汰最早过期的条目
+	if _, err := store.Get(ctx, "soon"); err != ErrTokenNotFound {
+		t.Fatalf("earliest expiring token should be evicted: %v", err)
+	}
+	if _, err := store.Get(ctx, "never"); err != nil {
+		t.Fatal(err)
+	}
+}
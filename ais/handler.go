@@ -0,0 +1,74 @@
+package ais
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/response"
+)
+
+// StateCookie 登录跳转时保存 state 的 cookie，回调时校验防止 CSRF
+const StateCookie = "ais_state"
+
+// LoginHandler 生成随机 state 写入 cookie 后跳转到授权页
+func LoginHandler(client *Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state, err := randomState()
+		if err != nil {
+			log.Ctx(c).Error("ais generate state failed", err)
+			response.FailWithStatus(http.StatusInternalServerError, response.InternalError, nil, c)
+			return
+		}
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(StateCookie, state, 300, "/", "", c.Request.TLS != nil, true)
+		c.Redirect(http.StatusFound, client.AuthCodeURL(state))
+	}
+}
+
+// CallbackHandler 校验 state 并用授权码换取 token，成功后交给 onLogin 处理（写会话、跳转等）。
+// onLogin 必填：token 含 refresh token，不能直接返回给浏览器
+func CallbackHandler(client *Client, onLogin func(c *gin.Context, token *Token)) gin.HandlerFunc {
+	if onLogin == nil {
+		panic("ais: CallbackHandler requires onLogin")
+	}
+	return func(c *gin.Context) {
+		if errCode := c.Query("error"); errCode != "" {
+			log.Ctx(c).Warn("ais authorize rejected", errCode+": "+c.Query("error_description"))
+			response.FailWithStatus(http.StatusUnauthorized, response.AuthenticationError, nil, c)
+			return
+		}
+
+		state, err := c.Cookie(StateCookie)
+		c.SetCookie(StateCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+		if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+			response.FailWithStatus(http.StatusBadRequest, response.BadRequest, nil, c)
+			return
+		}
+
+		code := c.Query("code")
+		if code == "" {
+			response.FailWithStatus(http.StatusBadRequest, response.BadRequest, nil, c)
+			return
+		}
+
+		token, err := client.Exchange(c.Request.Context(), code)
+		if err != nil {
+			log.Ctx(c).Warn("ais exchange token failed", err)
+			response.FailWithStatus(http.StatusUnauthorized, response.AuthenticationError, nil, c)
+			return
+		}
+		onLogin(c, token)
+	}
+}
+
+func randomState() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,202 @@
+package ais
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// TokenStore token 缓存
+type TokenStore interface {
+	Get(ctx context.Context, key string) (*Token, error)
+	Set(ctx context.Context, key string, token *Token) error
+	Delete(ctx context.Context, key string) error
+}
+
+// RefreshTokenTTL 带 refresh token 的 token 缓存时长，每次刷新后重新计时
+var RefreshTokenTTL = 30 * 24 * time.Hour
+
+// DefaultMemoryStoreSize 进程内缓存默认最多保存的条目数
+const DefaultMemoryStoreSize = 10000
+
+type memoryItem struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	items   map[string]memoryItem
+	maxSize int
+}
+
+func newMemoryStore(maxSize int) *memoryStore {
+	if maxSize <= 0 {
+		maxSize = DefaultMemoryStoreSize
+	}
+	return &memoryStore{items: make(map[string]memoryItem), maxSize: maxSize}
+}
+
+// NewMemoryStore 进程内 token 缓存，最多保存 DefaultMemoryStoreSize 个 token，多实例部署时使用 NewRedisStore
+func NewMemoryStore() TokenStore {
+	return newMemoryStore(DefaultMemoryStoreSize)
+}
+
+func (m *memoryStore) load(key string) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	if !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		delete(m.items, key)
+		return nil, false
+	}
+	return item.value, true
+}
+
+func (m *memoryStore) store(key string, value interface{}, ttl time.Duration) {
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[key]; !ok && len(m.items) >= m.maxSize {
+		m.evict()
+	}
+	m.items[key] = item
+}
+
+// evict 容量已满时先清理过期条目，仍然已满时淘汰最早过期的一条
+func (m *memoryStore) evict() {
+	now := time.Now()
+	var (
+		victim       string
+		victimExpire time.Time
+		found        bool
+	)
+	for key, item := range m.items {
+		if !item.expireAt.IsZero() && now.After(item.expireAt) {
+			delete(m.items, key)
+			continue
+		}
+		if !found || expiresBefore(item.expireAt, victimExpire) {
+			victim, victimExpire, found = key, item.expireAt, true
+		}
+	}
+	if found && len(m.items) >= m.maxSize {
+		delete(m.items, victim)
+	}
+}
+
+// expiresBefore 零值表示不过期，排在最后
+func expiresBefore(a, b time.Time) bool {
+	if a.IsZero() {
+		return false
+	}
+	return b.IsZero() || a.Before(b)
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (*Token, error) {
+	v, ok := m.load(key)
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return v.(*Token), nil
+}
+
+func (m *memoryStore) Set(ctx context.Context, key string, token *Token) error {
+	m.store(key, token, storeTTL(token))
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.items, key)
+	m.mu.Unlock()
+	return nil
+}
+
+type redisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisStore 基于 redis 的 token 缓存，key 前缀默认 ais:token:
+func NewRedisStore(pool *redis.Pool, prefix string) TokenStore {
+	if prefix == "" {
+		prefix = "ais:token:"
+	}
+	return &redisStore{pool: pool, prefix: prefix}
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) (*Token, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	data, err := redis.Bytes(redis.DoContext(conn, ctx, "GET", r.prefix+key))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if ttl := storeTTL(token); ttl > 0 {
+		_, err = redis.DoContext(conn, ctx, "SET", r.prefix+key, data, "PX", ttl.Milliseconds())
+	} else {
+		_, err = redis.DoContext(conn, ctx, "SET", r.prefix+key, data)
+	}
+	return err
+}
+
+func (r *redisStore) Delete(ctx context.Context, key string) error {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = redis.DoContext(conn, ctx, "DEL", r.prefix+key)
+	return err
+}
+
+// storeTTL 有 refresh token 时按 RefreshTokenTTL 缓存，access token 过期后由 Client.Token 刷新；
+// 否则随 access token 过期，expiry 为空时不设置过期
+func storeTTL(token *Token) time.Duration {
+	if token.RefreshToken != "" {
+		return RefreshTokenTTL
+	}
+	if token.Expiry.IsZero() {
+		return 0
+	}
+	if ttl := time.Until(token.Expiry); ttl > 0 {
+		return ttl
+	}
+	return time.Millisecond
+}
@@ -0,0 +1,408 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/errclass"
+	"github.com/hyzx-go/common-b2c/global"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// Module 告警模块自身的日志使用该模块名，不会再触发告警
+const Module = "alert"
+
+// 错误分类
+const (
+	ClassError     = "error"      // Error 及以上级别
+	ClassWarnError = "warn_error" // errclass 分类为 Warn 的错误，如唯一键冲突、连接被拒绝
+	ClassWarning   = "warning"    // 其他 Warn 日志
+)
+
+// 告警被抑制的原因
+const (
+	suppressSilenced    = "silenced"
+	suppressRateLimited = "rate_limited"
+)
+
+var (
+	alertSent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alert_sent_total",
+			Help: "Alert notifications sent, by sender and result",
+		},
+		[]string{"sender", "status"},
+	)
+	alertSuppressed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alert_suppressed_total",
+			Help: "Alerts not sent because of deduplication or rate limiting",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(alertSent)
+	prometheus.MustRegister(alertSuppressed)
+}
+
+// Rule 告警规则，日志同时满足级别、关键字与分类时计入聚合。
+// Classes 可填写通用分类 error/warn_error/warning，也可填写 errclass 注册的分类名
+type Rule struct {
+	Name      string       // 规则名，写入通知标题
+	Level     logrus.Level // 匹配该级别及更严重的日志，零值时为 Error
+	Keywords  []string     // 日志 keyword、message 或 error 包含任一关键字时匹配，为空时不限制
+	Classes   []string     // 错误分类，为空时不限制
+	Threshold int          // 窗口内同一聚合键出现的次数达到后才告警，默认 1
+}
+
+// DefaultRules 默认规则：Error 及以上的日志，以及 errclass 分类为 Warn 的错误
+var DefaultRules = []Rule{
+	{Name: "error", Level: logrus.ErrorLevel},
+	{Name: "warn_error", Level: logrus.WarnLevel, Classes: []string{ClassWarnError}},
+}
+
+// Options 告警配置
+type Options struct {
+	Rules         []Rule        // 为空时使用 DefaultRules
+	Window        time.Duration // 聚合窗口，窗口结束时发送，默认 1 分钟
+	Silence       time.Duration // 同一聚合键告警后的静默时长，默认 10 分钟，小于 0 时不静默
+	RatePerMinute int           // 每分钟最多发送的告警数，默认 20
+	Timeout       time.Duration // 单次发送超时，默认 5s
+}
+
+// Alert 一次告警，窗口内同一规则、分类与 keyword 的日志聚合为一条，keyword 中的数字与 id 不参与比较
+type Alert struct {
+	Rule    string    `json:"rule"`
+	Class   string    `json:"class"` // errclass 分类名，未分类时为通用分类
+	Level   string    `json:"level"`
+	Keyword string    `json:"keyword"`
+	Detail  string    `json:"detail,omitempty"` // 窗口内第一条日志的 error 或 message
+	TraceId string    `json:"trace_id,omitempty"`
+	Count   int       `json:"count"`
+	FirstAt time.Time `json:"first_at"`
+	LastAt  time.Time `json:"last_at"`
+	App     string    `json:"app,omitempty"`
+	Host    string    `json:"host,omitempty"`
+}
+
+// Title 通知标题
+func (a Alert) Title() string {
+	title := fmt.Sprintf("[%s] %s", strings.ToUpper(a.Level), a.Keyword)
+	if a.App != "" {
+		title = fmt.Sprintf("[%s]%s", a.App, title)
+	}
+	return title
+}
+
+// Text 通知正文，纯文本，各 sender 按需转换
+func (a Alert) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", a.Title())
+	fmt.Fprintf(&b, "rule: %s  class: %s\n", a.Rule, a.Class)
+	fmt.Fprintf(&b, "count: %d (%s ~ %s)\n", a.Count, a.FirstAt.Format(time.RFC3339), a.LastAt.Format(time.RFC3339))
+	if a.Host != "" {
+		fmt.Fprintf(&b, "host: %s\n", a.Host)
+	}
+	if a.TraceId != "" {
+		fmt.Fprintf(&b, "trace-id: %s\n", a.TraceId)
+	}
+	if a.Detail != "" {
+		fmt.Fprintf(&b, "detail: %s\n", a.Detail)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Hook 按规则聚合 Warn/Error 日志，窗口结束后去重、限流并发送通知。
+// 通过 log.AddHook 安装；Fire 只在内存中计数，发送在后台 goroutine 中进行。
+type Hook struct {
+	rules   []Rule
+	senders []Sender
+	opts    Options
+	limiter *rate.Limiter
+	levels  []logrus.Level
+
+	mu       sync.Mutex
+	groups   map[string]*group
+	silenced map[string]time.Time // 聚合键 -> 静默结束时间
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+type group struct {
+	alert     Alert
+	threshold int
+	timer     *time.Timer
+}
+
+// New 创建告警 hook，senders 为空时返回错误
+func New(opts Options, senders ...Sender) (*Hook, error) {
+	if len(senders) == 0 {
+		return nil, errors.New("alert requires at least one sender")
+	}
+	if len(opts.Rules) == 0 {
+		opts.Rules = DefaultRules
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.Silence == 0 {
+		opts.Silence = 10 * time.Minute
+	}
+	if opts.RatePerMinute <= 0 {
+		opts.RatePerMinute = 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	// 取所有规则中最详细的级别
+	lowest := logrus.ErrorLevel
+	rules := make([]Rule, len(opts.Rules))
+	for i, rule := range opts.Rules {
+		if rule.Level == logrus.PanicLevel {
+			rule.Level = logrus.ErrorLevel
+		}
+		if rule.Threshold <= 0 {
+			rule.Threshold = 1
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if rule.Level > lowest {
+			lowest = rule.Level
+		}
+		rules[i] = rule
+	}
+
+	return &Hook{
+		rules:    rules,
+		senders:  senders,
+		opts:     opts,
+		limiter:  rate.NewLimiter(rate.Every(time.Minute/time.Duration(opts.RatePerMinute)), opts.RatePerMinute),
+		levels:   logrus.AllLevels[:lowest+1],
+		groups:   map[string]*group{},
+		silenced: map[string]time.Time{},
+	}, nil
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if entry.Data["module"] == Module {
+		return nil
+	}
+	detail := entryDetail(entry)
+	generic, name := classify(entry, detail)
+	class := generic
+	if name != "" {
+		class = name
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	for _, rule := range h.rules {
+		if !rule.match(entry, generic, name, detail) {
+			continue
+		}
+		key := rule.Name + "|" + class + "|" + groupKeyword(entry.Message)
+		g, ok := h.groups[key]
+		if !ok {
+			g = &group{
+				alert: Alert{
+					Rule:    rule.Name,
+					Class:   class,
+					Level:   entry.Level.String(),
+					Keyword: entry.Message,
+					Detail:  detail,
+					TraceId: fieldString(entry.Data, log.TraceId),
+					FirstAt: entry.Time,
+					App:     fieldString(global.LogPreInfo, "app_name"),
+					Host:    fieldString(global.LogPreInfo, "host_name"),
+				},
+				threshold: rule.Threshold,
+			}
+			g.timer = time.AfterFunc(h.opts.Window, func() { h.flushGroup(key) })
+			h.groups[key] = g
+		}
+		g.alert.Count++
+		g.alert.LastAt = entry.Time
+	}
+	return nil
+}
+
+// Flush 立即结束所有窗口并发送，等待发送完成
+func (h *Hook) Flush() {
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.groups))
+	for key := range h.groups {
+		keys = append(keys, key)
+	}
+	h.mu.Unlock()
+
+	for _, key := range keys {
+		h.flushGroup(key)
+	}
+	h.wg.Wait()
+}
+
+// Close 发送未结束窗口中的告警，之后的日志不再处理
+func (h *Hook) Close() error {
+	h.Flush()
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *Hook) flushGroup(key string) {
+	h.mu.Lock()
+	g, ok := h.groups[key]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.groups, key)
+	g.timer.Stop()
+	if g.alert.Count < g.threshold {
+		h.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	for k, until := range h.silenced {
+		if !until.After(now) {
+			delete(h.silenced, k)
+		}
+	}
+	if _, ok := h.silenced[key]; ok {
+		h.mu.Unlock()
+		alertSuppressed.WithLabelValues(suppressSilenced).Inc()
+		return
+	}
+	if !h.limiter.Allow() {
+		h.mu.Unlock()
+		alertSuppressed.WithLabelValues(suppressRateLimited).Inc()
+		return
+	}
+	if h.opts.Silence > 0 {
+		h.silenced[key] = now.Add(h.opts.Silence)
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	defer h.wg.Done()
+	h.send(g.alert)
+}
+
+func (h *Hook) send(a Alert) {
+	for _, sender := range h.senders {
+		ctx, cancel := context.WithTimeout(context.Background(), h.opts.Timeout)
+		err := sender.Send(ctx, a)
+		cancel()
+		if err != nil {
+			alertSent.WithLabelValues(sender.Name(), "error").Inc()
+			log.Ctx(ctx).Module(Module).Warn("alert send failed", sender.Name(), err)
+			continue
+		}
+		alertSent.WithLabelValues(sender.Name(), "ok").Inc()
+	}
+}
+
+// classify 返回通用分类与 errclass 分类名。
+// logWrapper 与 GormLogger 会写入 error_class 字段；其他日志只能按错误文本匹配（Contains、Regexp 等）
+func classify(entry *logrus.Entry, detail string) (generic, name string) {
+	name = fieldString(entry.Data, log.ErrorClassField)
+	if name == "" && detail != "" {
+		if class, ok := errclass.Classify(errors.New(detail)); ok {
+			name = class.Name
+		}
+	}
+
+	switch {
+	case entry.Level <= logrus.ErrorLevel:
+		generic = ClassError
+	case name != "" || (detail != "" && log.IsWarnError(errors.New(detail))):
+		generic = ClassWarnError
+	default:
+		generic = ClassWarning
+	}
+	return generic, name
+}
+
+func (r Rule) match(entry *logrus.Entry, generic, name, detail string) bool {
+	if entry.Level > r.Level {
+		return false
+	}
+	if len(r.Classes) > 0 && !contains(r.Classes, generic) && (name == "" || !contains(r.Classes, name)) {
+		return false
+	}
+	if len(r.Keywords) == 0 {
+		return true
+	}
+	for _, keyword := range r.Keywords {
+		if strings.Contains(entry.Message, keyword) || strings.Contains(detail, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// variablePattern 日志内容中的 uuid、长十六进制串与数字，如订单号、用户 id
+var variablePattern = regexp.MustCompile(`[0-9a-fA-F]{8}(?:-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}|\b[0-9a-fA-F]{16,}\b|\d+`)
+
+// groupKeyword 聚合键使用的 keyword：Errorf 等格式化后的内容带有 id，替换为 * 后同一条日志才能聚合
+func groupKeyword(message string) string {
+	return variablePattern.ReplaceAllString(message, "*")
+}
+
+// entryDetail 取 error 字段，没有时取 message 字段
+func entryDetail(entry *logrus.Entry) string {
+	if err, ok := entry.Data[logrus.ErrorKey]; ok {
+		return fmt.Sprint(err)
+	}
+	switch message := entry.Data["message"].(type) {
+	case nil:
+		return ""
+	case string:
+		return message
+	case []interface{}:
+		parts := make([]string, len(message))
+		for i, m := range message {
+			parts[i] = fmt.Sprint(m)
+		}
+		return strings.Join(parts, " ")
+	default:
+		return fmt.Sprint(message)
+	}
+}
+
+func fieldString(fields logrus.Fields, key string) string {
+	if v, ok := fields[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// recorder 本地 HTTP 接收端，记录收到的请求
+type recorder struct {
+	mu       sync.Mutex
+	bodies   []map[string]interface{}
+	queries  []string
+	response string
+}
+
+func newRecorder(t *testing.T, response string) (*recorder, *httptest.Server) {
+	rec := &recorder{response: response}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		_ = json.Unmarshal(data, &body)
+		rec.mu.Lock()
+		rec.bodies = append(rec.bodies, body)
+		rec.queries = append(rec.queries, r.URL.RawQuery)
+		rec.mu.Unlock()
+		_, _ = io.WriteString(w, rec.response)
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv
+}
+
+func (r *recorder) received() []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]interface{}(nil), r.bodies...)
+}
+
+func newTestLogger(hook *Hook) *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	l.AddHook(hook)
+	return l
+}
+
+func TestHookAggregatesAndSilences(t *testing.T) {
+	rec, srv := newRecorder(t, "ok")
+	sender, err := NewSender(SenderConfig{Type: SenderWebhook, URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := New(Options{Window: time.Hour}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newTestLogger(hook)
+
+	for i := 0; i < 3; i++ {
+		l.WithField("message", "timeout").Error("pay failed")
+	}
+	l.Info("not an alert")
+	hook.Flush()
+
+	bodies := rec.received()
+	if len(bodies) != 1 {
+		t.Fatalf("want 1 aggregated alert, got %d", len(bodies))
+	}
+	if bodies[0]["count"] != float64(3) || bodies[0]["keyword"] != "pay failed" || bodies[0]["class"] != ClassError || bodies[0]["detail"] != "timeout" {
+		t.Fatalf("unexpected alert %v", bodies[0])
+	}
+
+	// 静默期内同一聚合键不再发送
+	l.Error("pay failed")
+	hook.Flush()
+	if n := len(rec.received()); n != 1 {
+		t.Fatalf("silenced alert was sent, got %d requests", n)
+	}
+}
+
+func TestHookGroupsFormattedMessages(t *testing.T) {
+	rec, srv := newRecorder(t, "ok")
+	sender, _ := NewSender(SenderConfig{Type: SenderWebhook, URL: srv.URL})
+	hook, _ := New(Options{Window: time.Hour}, sender)
+	l := newTestLogger(hook)
+
+	// Errorf 渲染后的内容带有不同的 id，仍聚合为一条
+	l.Errorf("pay order %d failed", 1790123456789012345)
+	l.Errorf("pay order %d failed", 1790123456789012346)
+	l.Errorf("refund %s failed", "3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f")
+	l.Errorf("refund %s failed", "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d")
+	hook.Flush()
+
+	bodies := rec.received()
+	if len(bodies) != 2 {
+		t.Fatalf("want 2 aggregated alerts, got %v", bodies)
+	}
+	for _, body := range bodies {
+		if body["count"] != float64(2) {
+			t.Fatalf("formatted messages not grouped: %v", body)
+		}
+	}
+}
+
+func TestHookWindowAndRules(t *testing.T) {
+	rec, srv := newRecorder(t, "ok")
+	sender, _ := NewSender(SenderConfig{Type: SenderWebhook, URL: srv.URL})
+	hook, err := New(Options{
+		Window: 20 * time.Millisecond,
+		Rules: append([]Rule{
+			{Name: "payment", Level: logrus.WarnLevel, Keywords: []string{"pay"}, Threshold: 2},
+		}, DefaultRules...),
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newTestLogger(hook)
+
+	l.WithError(gorm.ErrDuplicatedKey).Warn("create order")
+	l.WithError(errors.New("bad input")).Warn("create order") // 普通 warning 不匹配默认规则
+	l.Warn("pay slow")                                        // 未达到阈值
+	l.Warn("refund slow")
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	hook.Flush()
+
+	bodies := rec.received()
+	if len(bodies) != 1 || bodies[0]["rule"] != "warn_error" || bodies[0]["class"] != ClassWarnError {
+		t.Fatalf("unexpected alerts %v", bodies)
+	}
+}
+
+func TestHookRateLimit(t *testing.T) {
+	rec, srv := newRecorder(t, "ok")
+	sender, _ := NewSender(SenderConfig{Type: SenderWebhook, URL: srv.URL})
+	hook, _ := New(Options{Window: time.Hour, RatePerMinute: 1}, sender)
+	l := newTestLogger(hook)
+
+	l.Error("first")
+	l.Error("second")
+	hook.Flush()
+	if n := len(rec.received()); n != 1 {
+		t.Fatalf("rate limit exceeded: %d alerts sent", n)
+	}
+}
+
+func TestSenderFormats(t *testing.T) {
+	a := Alert{Rule: "error", Class: ClassError, Level: "error", Keyword: "pay failed", Count: 2, FirstAt: time.Now(), LastAt: time.Now()}
+
+	rec, srv := newRecorder(t, `{"errcode":0,"errmsg":"ok"}`)
+	ding, _ := NewSender(SenderConfig{Type: SenderDingTalk, URL: srv.URL + "?access_token=x", Secret: "s"})
+	if err := ding.Send(t.Context(), a); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.received()[0]
+	if body["msgtype"] != "text" || !strings.Contains(body["text"].(map[string]interface{})["content"].(string), "pay failed") {
+		t.Fatalf("unexpected dingtalk body %v", body)
+	}
+	if q := rec.queries[0]; !strings.Contains(q, "access_token=x") || !strings.Contains(q, "sign=") || !strings.Contains(q, "timestamp=") {
+		t.Fatalf("dingtalk query not signed: %s", q)
+	}
+
+	rec, srv = newRecorder(t, `{"code":19021,"msg":"sign match fail"}`)
+	feishu, _ := NewSender(SenderConfig{Type: SenderFeishu, URL: srv.URL, Secret: "s"})
+	if err := feishu.Send(t.Context(), a); err == nil || !strings.Contains(err.Error(), "19021") {
+		t.Fatalf("feishu error code not reported: %v", err)
+	}
+	if body = rec.received()[0]; body["msg_type"] != "text" || body["sign"] == nil {
+		t.Fatalf("unexpected feishu body %v", body)
+	}
+
+	rec, srv = newRecorder(t, "ok")
+	slack, _ := NewSender(SenderConfig{Type: SenderSlack, URL: srv.URL})
+	if err := slack.Send(t.Context(), a); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := rec.received()[0]["text"].(string); !strings.HasPrefix(text, "[ERROR] pay failed") {
+		t.Fatalf("unexpected slack text %q", text)
+	}
+
+	if _, err := NewSender(SenderConfig{Type: "sms", URL: srv.URL}); err == nil {
+		t.Fatal("unknown sender type accepted")
+	}
+}
+
+func TestHookErrorClassRule(t *testing.T) {
+	rec, srv := newRecorder(t, "ok")
+	sender, _ := NewSender(SenderConfig{Type: SenderWebhook, URL: srv.URL})
+	hook, _ := New(Options{Window: time.Hour, Rules: []Rule{
+		{Name: "db", Level: logrus.WarnLevel, Classes: []string{"db_unavailable"}},
+	}}, sender)
+	l := newTestLogger(hook)
+
+	// logWrapper 写入的 error_class 字段
+	l.WithField(log.ErrorClassField, "duplicated_key").Warn("create order")
+	// 只有错误文本时按 errclass 的 Contains 规则分类
+	l.WithField("message", "dial tcp: connect: connection refused").Warn("query order")
+	hook.Flush()
+
+	bodies := rec.received()
+	if len(bodies) != 1 || bodies[0]["class"] != "db_unavailable" || bodies[0]["keyword"] != "query order" {
+		t.Fatalf("unexpected alerts %v", bodies)
+	}
+}
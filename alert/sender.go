@@ -0,0 +1,186 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 通知渠道
+const (
+	SenderWebhook  = "webhook"  // 请求体为 Alert 的 JSON
+	SenderDingTalk = "dingtalk" // 钉钉群机器人
+	SenderFeishu   = "feishu"   // 飞书群机器人
+	SenderSlack    = "slack"    // Slack incoming webhook
+)
+
+// Sender 告警通知渠道
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, a Alert) error
+}
+
+// SenderConfig 通知渠道配置
+type SenderConfig struct {
+	Type    string            // webhook/dingtalk/feishu/slack
+	Name    string            // 指标中的 sender 标签，默认与 Type 相同
+	URL     string            // 机器人或 webhook 地址
+	Secret  string            // dingtalk/feishu：加签密钥，为空时不签名
+	Headers map[string]string // webhook：附加请求头，如鉴权
+}
+
+// NewSender 按配置创建通知渠道
+func NewSender(conf SenderConfig) (Sender, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("alert %s sender requires url", conf.Type)
+	}
+	s := &webhookSender{
+		name:    conf.Name,
+		kind:    conf.Type,
+		url:     conf.URL,
+		secret:  conf.Secret,
+		headers: conf.Headers,
+		client:  http.DefaultClient,
+	}
+	if s.name == "" {
+		s.name = conf.Type
+	}
+	switch conf.Type {
+	case SenderWebhook, SenderDingTalk, SenderFeishu, SenderSlack:
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown alert sender type %q", conf.Type)
+	}
+}
+
+// webhookSender 各渠道都是一次 JSON POST，只有请求体、签名与响应校验不同
+type webhookSender struct {
+	name    string
+	kind    string
+	url     string
+	secret  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *webhookSender) Name() string {
+	return s.name
+}
+
+func (s *webhookSender) Send(ctx context.Context, a Alert) error {
+	target, body, err := s.payload(a, time.Now())
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert %s: status %d: %s", s.name, resp.StatusCode, respBody)
+	}
+	return s.checkResponse(respBody)
+}
+
+// payload 返回请求地址与请求体
+func (s *webhookSender) payload(a Alert, now time.Time) (string, interface{}, error) {
+	text := a.Text()
+	switch s.kind {
+	case SenderDingTalk:
+		target := s.url
+		if s.secret != "" {
+			// 钉钉加签：timestamp 为毫秒，签名放在查询参数中
+			timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+			sign := hmacBase64([]byte(s.secret), timestamp+"\n"+s.secret)
+			u, err := url.Parse(s.url)
+			if err != nil {
+				return "", nil, err
+			}
+			q := u.Query()
+			q.Set("timestamp", timestamp)
+			q.Set("sign", sign)
+			u.RawQuery = q.Encode()
+			target = u.String()
+		}
+		return target, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}, nil
+	case SenderFeishu:
+		body := map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]string{"text": text},
+		}
+		if s.secret != "" {
+			// 飞书加签：timestamp 为秒，以 timestamp\nsecret 为密钥对空串签名
+			timestamp := strconv.FormatInt(now.Unix(), 10)
+			body["timestamp"] = timestamp
+			body["sign"] = hmacBase64([]byte(timestamp+"\n"+s.secret), "")
+		}
+		return s.url, body, nil
+	case SenderSlack:
+		return s.url, map[string]string{"text": text}, nil
+	default:
+		return s.url, a, nil
+	}
+}
+
+// checkResponse 钉钉、飞书在 HTTP 200 中通过错误码返回失败
+func (s *webhookSender) checkResponse(body []byte) error {
+	switch s.kind {
+	case SenderDingTalk:
+		var resp struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("alert %s: decode response: %w", s.name, err)
+		}
+		if resp.ErrCode != 0 {
+			return fmt.Errorf("alert %s: errcode %d: %s", s.name, resp.ErrCode, resp.ErrMsg)
+		}
+	case SenderFeishu:
+		var resp struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("alert %s: decode response: %w", s.name, err)
+		}
+		if resp.Code != 0 {
+			return fmt.Errorf("alert %s: code %d: %s", s.name, resp.Code, resp.Msg)
+		}
+	}
+	return nil
+}
+
+func hmacBase64(key []byte, data string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Audience aud 既可以是字符串也可以是字符串数组
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return fmt.Errorf("aud must be string or array: %w", err)
+	}
+	*a = multi
+	return nil
+}
+
+func (a Audience) Contains(aud string) bool {
+	for _, item := range a {
+		if item == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims JWT 载荷
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Id        string   `json:"jti,omitempty"`
+
+	UserId   string   `json:"uid,omitempty"`
+	TenantId string   `json:"tenant_id,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Tier     string   `json:"tier,omitempty"`
+
+	// Extra 全部原始字段，便于读取自定义 claim
+	Extra map[string]interface{} `json:"-"`
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type alias Claims
+	var raw struct {
+		alias
+		UserId interface{} `json:"uid,omitempty"`
+	}
+	// 数字按 json.Number 解析，避免超过 2^53 的雪花 ID 经 float64 丢失精度
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	*c = Claims(raw.alias)
+
+	// uid 可能是数字，统一转成字符串；没有 uid 时使用 sub
+	switch v := raw.UserId.(type) {
+	case string:
+		c.UserId = v
+	case json.Number:
+		c.UserId = v.String()
+	}
+	if c.UserId == "" {
+		c.UserId = c.Subject
+	}
+
+	return json.Unmarshal(data, &c.Extra)
+}
+
+type claimsCtxKey struct{}
+
+// GinClaimsKey 认证中间件写入 gin.Context 的 claims key
+const GinClaimsKey = "auth_claims"
+
+// WithClaims 将 claims 写入 context
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, claims)
+}
+
+// FromContext 从 context 中读取 claims，gin.Context 同样适用
+func FromContext(ctx context.Context) (*Claims, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	// gin.Context.Value 对字符串 key 读取 c.Get，不依赖 gin 各版本 Get 方法的签名
+	if claims, ok := ctx.Value(GinClaimsKey).(*Claims); ok {
+		return claims, true
+	}
+	claims, ok := ctx.Value(claimsCtxKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func signWith(t *testing.T, h header, claims interface{}, sign func(digest []byte) []byte) string {
+	t.Helper()
+	hb, _ := json.Marshal(h)
+	pb, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(pb)
+	digest := sha256.Sum256([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(digest[:]))
+}
+
+func TestVerifyHS256(t *testing.T) {
+	secret := []byte("secret")
+	v, err := NewVerifier(Options{Algorithm: HS256, Secret: secret, Issuer: "b2c", Audience: []string{"api"}, Leeway: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+
+	token, _ := SignHS256(map[string]interface{}{
+		"iss": "b2c", "aud": "api", "sub": "u1", "uid": 1001, "exp": now + 60, "roles": []string{"admin"}, "scope": "read",
+	}, secret)
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserId != "1001" || claims.Roles[0] != "admin" || claims.Extra["scope"] != "read" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	cases := []struct {
+		claims map[string]interface{}
+		secret []byte
+		want   error
+	}{
+		{map[string]interface{}{"iss": "b2c", "aud": "api", "exp": now - 10}, secret, ErrTokenExpired},
+		{map[string]interface{}{"iss": "b2c", "aud": "api", "nbf": now + 10}, secret, ErrTokenNotValidYet},
+		{map[string]interface{}{"iss": "other", "aud": "api"}, secret, ErrTokenIssuer},
+		{map[string]interface{}{"iss": "b2c", "aud": []string{"web"}}, secret, ErrTokenAudience},
+		{map[string]interface{}{"iss": "b2c", "aud": "api"}, []byte("wrong"), ErrTokenSignature},
+	}
+	for _, cs := range cases {
+		token, _ := SignHS256(cs.claims, cs.secret)
+		if _, err := v.Verify(token); !errors.Is(err, cs.want) {
+			t.Errorf("claims %v: err=%v want %v", cs.claims, err, cs.want)
+		}
+	}
+
+	if _, err := v.Verify("a.b"); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("malformed token: %v", err)
+	}
+}
+
+func TestVerifyRS256AndES256(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	claims := map[string]interface{}{"sub": "u2", "exp": time.Now().Unix() + 60}
+
+	rv, _ := NewVerifier(Options{Algorithm: RS256, Keys: map[string]crypto.PublicKey{"r1": &rsaKey.PublicKey}})
+	token := signWith(t, header{Alg: RS256, Kid: "r1"}, claims, func(digest []byte) []byte {
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+		return sig
+	})
+	if c, err := rv.Verify(token); err != nil || c.UserId != "u2" {
+		t.Fatalf("rs256: %v %+v", err, c)
+	}
+
+	ev, _ := NewVerifier(Options{Algorithm: ES256, Keys: map[string]crypto.PublicKey{"e1": &ecKey.PublicKey}})
+	token = signWith(t, header{Alg: ES256, Kid: "e1"}, claims, func(digest []byte) []byte {
+		r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest)
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	})
+	if _, err := ev.Verify(token); err != nil {
+		t.Fatalf("es256: %v", err)
+	}
+
+	// 算法与配置不一致时拒绝，防止 alg 混淆
+	hsToken, _ := SignHS256(claims, []byte("secret"))
+	if _, err := rv.Verify(hsToken); !errors.Is(err, ErrTokenAlgorithm) {
+		t.Fatalf("alg confusion: %v", err)
+	}
+}
+
+func TestClaimsSnowflakeUserId(t *testing.T) {
+	var claims Claims
+	if err := json.Unmarshal([]byte(`{"uid": 1790123456789012345, "exp": 1700000000}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserId != "1790123456789012345" || claims.ExpiresAt != 1700000000 {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
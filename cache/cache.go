@@ -0,0 +1,390 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound loader 返回该错误表示数据不存在，结果会被短暂缓存（负缓存）
+var ErrNotFound = errors.New("cache: not found")
+
+// ErrTypeMismatch 同一个 key 被不同类型的 GetOrLoad 并发加载，共享到的结果不是调用方需要的类型
+var ErrTypeMismatch = errors.New("cache: type mismatch")
+
+const (
+	markValue    byte = 'v'
+	markNegative byte = 'n'
+)
+
+// Options 二级缓存配置
+type Options struct {
+	// Prefix Redis key 前缀
+	Prefix string
+	// LocalSize 本地 LRU 容量，0 表示关闭本地缓存
+	LocalSize int
+	// LocalTTL 本地缓存的最长存活时间，实际取 min(LocalTTL, ttl)
+	LocalTTL time.Duration
+	// NegativeTTL 负缓存存活时间
+	NegativeTTL time.Duration
+	// Jitter TTL 随机抖动比例，避免大量 key 同时过期
+	Jitter float64
+	// Channel 跨实例失效通知的 pub/sub 频道
+	Channel string
+}
+
+type Option func(*Options)
+
+func SetPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.Prefix = prefix
+	}
+}
+
+func SetLocalSize(size int) Option {
+	return func(o *Options) {
+		o.LocalSize = size
+	}
+}
+
+func SetLocalTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.LocalTTL = ttl
+	}
+}
+
+func SetNegativeTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.NegativeTTL = ttl
+	}
+}
+
+func SetJitter(jitter float64) Option {
+	return func(o *Options) {
+		o.Jitter = jitter
+	}
+}
+
+func SetChannel(channel string) Option {
+	return func(o *Options) {
+		o.Channel = channel
+	}
+}
+
+// Cache 进程内 LRU + Redis 二级缓存
+type Cache struct {
+	pool    *redis.Pool
+	local   *localCache
+	group   singleflight.Group
+	options Options
+
+	mu     sync.Mutex
+	psc    *redis.PubSubConn
+	closed bool
+	done   chan struct{}
+}
+
+// New 使用指定的连接池创建缓存，pool 为 nil 时只使用本地缓存
+func New(pool *redis.Pool, opts ...Option) *Cache {
+	options := Options{
+		Prefix:      "cache:",
+		LocalSize:   10000,
+		LocalTTL:    time.Minute,
+		NegativeTTL: 30 * time.Second,
+		Jitter:      0.1,
+		Channel:     "cache:invalidate",
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	c := &Cache{
+		pool:    pool,
+		local:   newLocalCache(options.LocalSize),
+		options: options,
+		done:    make(chan struct{}),
+	}
+	if pool != nil && options.LocalSize > 0 {
+		go c.subscribe()
+	} else {
+		close(c.done)
+	}
+	return c
+}
+
+// NewWithIns 使用配置中 RedisList 的实例名创建缓存
+func NewWithIns(insName string, opts ...Option) (*Cache, error) {
+	pool, err := config.GetRedisPool(insName)
+	if err != nil {
+		return nil, err
+	}
+	return New(pool, opts...), nil
+}
+
+// GetOrLoad 依次查询本地缓存、Redis，都未命中时调用 loader 加载并回填。
+// 同一个 key 的并发未命中只会调用一次 loader，共享第一个调用方的 ctx。
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	fullKey := c.options.Prefix + key
+
+	if entry, ok := c.local.get(fullKey); ok {
+		if entry.negative {
+			return zero, ErrNotFound
+		}
+		if v, ok := entry.value.(T); ok {
+			return v, nil
+		}
+		// 本地缓存的类型与 T 不同时按编码后的值解析
+		var v T
+		if negative, err := decode(entry.raw, &v); err == nil {
+			if negative {
+				return zero, ErrNotFound
+			}
+			return v, nil
+		}
+	}
+
+	res, err, _ := c.group.Do(fullKey, func() (interface{}, error) {
+		if raw, ok := c.getRemote(ctx, fullKey); ok {
+			var v T
+			negative, err := decode(raw, &v)
+			if err == nil {
+				c.setLocal(fullKey, v, raw, negative, ttl)
+				if negative {
+					return nil, ErrNotFound
+				}
+				return v, nil
+			}
+			log.Ctx(ctx).Warn(fmt.Sprintf("cache decode failed:%s", fullKey), err)
+		}
+
+		v, err := loader(ctx)
+		if errors.Is(err, ErrNotFound) {
+			raw := []byte{markNegative}
+			c.setRemote(ctx, fullKey, raw, c.options.NegativeTTL)
+			c.setLocal(fullKey, nil, raw, true, c.options.NegativeTTL)
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("cache: encode: %w", err)
+		}
+		raw := append([]byte{markValue}, data...)
+		c.setRemote(ctx, fullKey, raw, ttl)
+		c.setLocal(fullKey, v, raw, false, ttl)
+		return v, nil
+	})
+	if err != nil {
+		return zero, err
+	}
+	if v, ok := res.(T); ok || res == nil {
+		return v, nil
+	}
+	return zero, fmt.Errorf("%w: key %s loaded as %T, want %s", ErrTypeMismatch, key, res, reflect.TypeOf(&zero).Elem())
+}
+
+// Delete 删除缓存，并通知其他实例清除本地缓存
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	fullKeys := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		fullKeys = append(fullKeys, c.options.Prefix+key)
+		args = append(args, c.options.Prefix+key)
+	}
+	c.local.delete(fullKeys...)
+
+	if c.pool == nil {
+		return nil
+	}
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("cache: get conn: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := redis.DoContext(conn, ctx, "DEL", args...); err != nil {
+		return fmt.Errorf("cache: delete: %w", err)
+	}
+
+	payload, _ := json.Marshal(fullKeys)
+	if _, err := redis.DoContext(conn, ctx, "PUBLISH", c.options.Channel, payload); err != nil {
+		return fmt.Errorf("cache: publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Close 停止失效订阅
+func (c *Cache) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	if c.psc != nil {
+		c.psc.Close()
+	}
+	c.mu.Unlock()
+	<-c.done
+}
+
+func (c *Cache) getRemote(ctx context.Context, key string) ([]byte, bool) {
+	if c.pool == nil {
+		return nil, false
+	}
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warn("cache get conn failed", err)
+		return nil, false
+	}
+	defer conn.Close()
+
+	raw, err := redis.Bytes(redis.DoContext(conn, ctx, "GET", key))
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			log.Ctx(ctx).Warn(fmt.Sprintf("cache get failed:%s", key), err)
+		}
+		return nil, false
+	}
+	return raw, true
+}
+
+func (c *Cache) setRemote(ctx context.Context, key string, raw []byte, ttl time.Duration) {
+	if c.pool == nil {
+		return
+	}
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warn("cache get conn failed", err)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := redis.DoContext(conn, ctx, "SET", key, raw, "PX", c.jitter(ttl).Milliseconds()); err != nil {
+		log.Ctx(ctx).Warn(fmt.Sprintf("cache set failed:%s", key), err)
+	}
+}
+
+func (c *Cache) setLocal(key string, value interface{}, raw []byte, negative bool, ttl time.Duration) {
+	if ttl > c.options.LocalTTL {
+		ttl = c.options.LocalTTL
+	}
+	c.local.set(&localEntry{
+		key:      key,
+		value:    value,
+		raw:      raw,
+		negative: negative,
+		expireAt: time.Now().Add(c.jitter(ttl)),
+	})
+}
+
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if c.options.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := time.Duration((rand.Float64()*2 - 1) * c.options.Jitter * float64(ttl))
+	if ttl+delta <= 0 {
+		return ttl
+	}
+	return ttl + delta
+}
+
+// subscribe 订阅失效频道，连接断开后自动重连
+func (c *Cache) subscribe() {
+	defer close(c.done)
+
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return
+		}
+		// 使用独立连接订阅，连接池的连接在 Close 时会等待退订响应，与 Receive 并发读冲突
+		conn, err := c.dial()
+		if err != nil {
+			c.mu.Unlock()
+			log.Ctx(nil).Warn("cache subscribe dial failed", err)
+		} else {
+			psc := &redis.PubSubConn{Conn: conn}
+			c.psc = psc
+			c.mu.Unlock()
+
+			if err := psc.Subscribe(c.options.Channel); err != nil {
+				log.Ctx(nil).Warn("cache subscribe failed", err)
+			} else {
+				c.receive(psc)
+			}
+			psc.Close()
+		}
+
+		// 断线后清空本地缓存，避免错过失效通知导致脏读
+		c.local.purge()
+
+		c.mu.Lock()
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (c *Cache) dial() (redis.Conn, error) {
+	if c.pool.DialContext != nil {
+		return c.pool.DialContext(context.Background())
+	}
+	return c.pool.Dial()
+}
+
+func (c *Cache) receive(psc *redis.PubSubConn) {
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			var keys []string
+			if err := json.Unmarshal(v.Data, &keys); err != nil {
+				log.Ctx(nil).Warn("cache invalidation payload invalid", err)
+				continue
+			}
+			c.local.delete(keys...)
+		case error:
+			c.mu.Lock()
+			closed := c.closed
+			c.mu.Unlock()
+			if !closed {
+				log.Ctx(nil).Warn("cache subscription interrupted", v)
+			}
+			return
+		}
+	}
+}
+
+func decode(raw []byte, v interface{}) (negative bool, err error) {
+	if len(raw) == 0 {
+		return false, errors.New("cache: empty value")
+	}
+	switch raw[0] {
+	case markNegative:
+		return true, nil
+	case markValue:
+		return false, json.Unmarshal(raw[1:], v)
+	}
+	return false, fmt.Errorf("cache: unknown mark %q", raw[0])
+}
@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+type user struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTestPool(t *testing.T, mr *miniredis.Miniredis) *redis.Pool {
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func waitSubscribed(t *testing.T, mr *miniredis.Miniredis, channel string, n int) {
+	deadline := time.Now().Add(time.Second)
+	for mr.PubSubNumSub(channel)[channel] < n {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber not ready")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := New(newTestPool(t, mr))
+	defer c.Close()
+
+	var calls int32
+	loader := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return user{Id: 1, Name: "tom"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := GetOrLoad(context.Background(), c, "user:1", time.Minute, loader)
+			if err != nil || u.Name != "tom" {
+				t.Errorf("GetOrLoad: %+v %v", u, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+	if !mr.Exists("cache:user:1") {
+		t.Fatal("value should be written to redis")
+	}
+}
+
+func TestGetOrLoadTypeMismatch(t *testing.T) {
+	c := New(nil)
+	defer c.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, _ = GetOrLoad(context.Background(), c, "user:1", time.Minute, func(ctx context.Context) (user, error) {
+			close(started)
+			<-release
+			return user{Id: 1}, nil
+		})
+	}()
+	<-started
+
+	// 同一个 key 以不同类型并发加载时共享到 user，返回类型错误而不是零值
+	done := make(chan error)
+	go func() {
+		_, err := GetOrLoad(context.Background(), c, "user:1", time.Minute, func(ctx context.Context) (string, error) {
+			return "tom", nil
+		})
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-done; !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("want ErrTypeMismatch, got %v", err)
+	}
+}
+
+func TestGetOrLoadLocalHitOtherType(t *testing.T) {
+	c := New(nil)
+	defer c.Close()
+
+	want := user{Id: 1, Name: "tom"}
+	if _, err := GetOrLoad(context.Background(), c, "user:1", time.Minute, func(ctx context.Context) (user, error) {
+		return want, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地缓存中是 user，以 map 类型读取时解析编码后的值，不再调用 loader
+	var calls int32
+	got, err := GetOrLoad(context.Background(), c, "user:1", time.Minute, func(ctx context.Context) (map[string]interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("loader called %d times on local hit", calls)
+	}
+	if got["name"] != "tom" || got["id"] != float64(1) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestGetOrLoadFromRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newTestPool(t, mr)
+	writer := New(pool, SetLocalSize(0))
+	reader := New(pool, SetLocalSize(0))
+
+	_, err := GetOrLoad(context.Background(), writer, "user:2", time.Minute, func(ctx context.Context) (user, error) {
+		return user{Id: 2, Name: "jerry"}, nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrLoad: %v", err)
+	}
+
+	u, err := GetOrLoad(context.Background(), reader, "user:2", time.Minute, func(ctx context.Context) (user, error) {
+		return user{}, errors.New("loader must not be called")
+	})
+	if err != nil || u.Name != "jerry" {
+		t.Fatalf("GetOrLoad from redis: %+v %v", u, err)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := New(newTestPool(t, mr), SetNegativeTTL(time.Second))
+	defer c.Close()
+
+	var calls int32
+	loader := func(ctx context.Context) (*user, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrNotFound
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := GetOrLoad(context.Background(), c, "user:404", time.Minute, loader); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+	if ttl := mr.TTL("cache:user:404"); ttl <= 0 || ttl > 1100*time.Millisecond {
+		t.Fatalf("unexpected negative ttl %v", ttl)
+	}
+}
+
+func TestLoaderErrorNotCached(t *testing.T) {
+	c := New(nil)
+
+	var calls int32
+	loader := func(ctx context.Context) (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 0, errors.New("db down")
+		}
+		return 42, nil
+	}
+	if _, err := GetOrLoad(context.Background(), c, "n", time.Minute, loader); err == nil {
+		t.Fatal("expected loader error")
+	}
+	n, err := GetOrLoad(context.Background(), c, "n", time.Minute, loader)
+	if err != nil || n != 42 {
+		t.Fatalf("GetOrLoad: %v %v", n, err)
+	}
+}
+
+func TestInvalidateAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := newTestPool(t, mr)
+	a := New(pool)
+	defer a.Close()
+	b := New(pool)
+	defer b.Close()
+	waitSubscribed(t, mr, "cache:invalidate", 2)
+
+	load := func(name string) func(ctx context.Context) (user, error) {
+		return func(ctx context.Context) (user, error) {
+			return user{Id: 3, Name: name}, nil
+		}
+	}
+	if _, err := GetOrLoad(context.Background(), b, "user:3", time.Minute, load("v1")); err != nil {
+		t.Fatalf("GetOrLoad: %v", err)
+	}
+	if b.local.len() != 1 {
+		t.Fatal("value should be cached locally")
+	}
+
+	if err := a.Delete(context.Background(), "user:3"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for b.local.len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("local cache of other instance was not invalidated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	u, _ := GetOrLoad(context.Background(), b, "user:3", time.Minute, load("v2"))
+	if u.Name != "v2" {
+		t.Fatalf("expected reload after invalidation, got %+v", u)
+	}
+}
+
+func TestLocalLRUEviction(t *testing.T) {
+	l := newLocalCache(2)
+	for _, key := range []string{"a", "b", "c"} {
+		l.set(&localEntry{key: key, expireAt: time.Now().Add(time.Minute)})
+	}
+	if _, ok := l.get("a"); ok {
+		t.Fatal("oldest entry should be evicted")
+	}
+	if _, ok := l.get("c"); !ok {
+		t.Fatal("newest entry should be kept")
+	}
+
+	l.set(&localEntry{key: "expired", expireAt: time.Now().Add(-time.Second)})
+	if _, ok := l.get("expired"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+}
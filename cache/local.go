@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// localEntry 本地缓存条目
+type localEntry struct {
+	key      string
+	value    interface{} // 已解码的值，类型匹配时可直接返回
+	raw      []byte      // 编码后的值，类型不匹配时重新解码
+	negative bool
+	expireAt time.Time
+}
+
+// localCache 带 TTL 的进程内 LRU 缓存
+type localCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newLocalCache(capacity int) *localCache {
+	return &localCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (l *localCache) get(key string) (*localEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*localEntry)
+	if time.Now().After(entry.expireAt) {
+		l.removeElement(elem)
+		return nil, false
+	}
+	l.ll.MoveToFront(elem)
+	return entry, true
+}
+
+func (l *localCache) set(entry *localEntry) {
+	if l.capacity <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[entry.key]; ok {
+		elem.Value = entry
+		l.ll.MoveToFront(elem)
+		return
+	}
+
+	l.items[entry.key] = l.ll.PushFront(entry)
+	for l.ll.Len() > l.capacity {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+func (l *localCache) delete(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := l.items[key]; ok {
+			l.removeElement(elem)
+		}
+	}
+}
+
+func (l *localCache) purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+}
+
+func (l *localCache) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *localCache) removeElement(elem *list.Element) {
+	l.ll.Remove(elem)
+	delete(l.items, elem.Value.(*localEntry).key)
+}
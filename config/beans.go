@@ -1,13 +1,21 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/hyzx-go/common-b2c/alert"
 	"github.com/hyzx-go/common-b2c/global"
 	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/signature"
+	"github.com/hyzx-go/common-b2c/tracing"
 	"github.com/hyzx-go/common-b2c/utils"
 	"github.com/sirupsen/logrus"
+	gLog "gorm.io/gorm/logger"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 )
 
 var (
@@ -27,24 +35,45 @@ type BeanFactory interface {
 }
 
 const (
-	_defaultLogKey        = "log"
-	_defaultMysqlKey      = "mysql"
-	_defaultRedisKey      = "redis"
-	_defaultSystemKey     = "system"
-	_defaultAisKey        = "ais"
-	_defaultOssKey        = "oss"
-	_defaultHttpClientKey = "httpClient"
+	_defaultLogKey          = "log"
+	_defaultMysqlKey        = "mysql"
+	_defaultRedisKey        = "redis"
+	_defaultSystemKey       = "system"
+	_defaultAisKey          = "ais"
+	_defaultOssKey          = "oss"
+	_defaultHttpClientKey   = "httpClient"
+	_defaultRateLimitKey    = "rate_limit"
+	_defaultCorsKey         = "cors"
+	_defaultRequestLimitKey = "request_limit"
+	_defaultAuthKey         = "auth"
+	_defaultRbacKey         = "rbac"
+	_defaultSignKey         = "sign"
+	_defaultIdempotencyKey  = "idempotency"
+	_defaultTracingKey      = "tracing"
+	_defaultRequestIdKey    = "request_id"
+	_defaultAlertKey        = "alert"
 )
 
 func (p *parser) initBeanKeys() {
 	p.beanKeys = []string{
 		_defaultSystemKey,
 		_defaultLogKey,
+		_defaultAlertKey,
+		// 需在 mysql、redis、httpClient 之前初始化，以便为其挂上链路追踪
+		_defaultTracingKey,
 		_defaultMysqlKey,
 		_defaultRedisKey,
 		_defaultHttpClientKey,
 		_defaultOssKey,
 		_defaultAisKey,
+		_defaultRateLimitKey,
+		_defaultCorsKey,
+		_defaultRequestLimitKey,
+		_defaultAuthKey,
+		_defaultRbacKey,
+		_defaultSignKey,
+		_defaultIdempotencyKey,
+		_defaultRequestIdKey,
 	}
 }
 
@@ -64,6 +93,26 @@ func getBeanFactory(key string) BeanFactory {
 		return &OssConf{}
 	case _defaultHttpClientKey:
 		return &HttpClientConf{}
+	case _defaultRateLimitKey:
+		return &RateLimitConf{}
+	case _defaultCorsKey:
+		return &CorsConf{}
+	case _defaultRequestLimitKey:
+		return &RequestLimitConf{}
+	case _defaultAuthKey:
+		return &AuthConf{}
+	case _defaultRbacKey:
+		return &RbacConf{}
+	case _defaultSignKey:
+		return &SignConf{}
+	case _defaultIdempotencyKey:
+		return &IdempotencyConf{}
+	case _defaultTracingKey:
+		return &TracingConf{}
+	case _defaultRequestIdKey:
+		return &RequestIdConf{}
+	case _defaultAlertKey:
+		return &AlertConf{}
 	default:
 		log.GetLogger().Error(fmt.Sprintf("cannot find this key %s's beanFactory", key))
 	}
@@ -118,18 +167,560 @@ func (c *LogConf) Initialize(inConfig bool, p *parser) error {
 		panic(errors.New("please check log config"))
 	}
 
+	level, modules, err := c.levels()
+	if err != nil {
+		return err
+	}
+	sinks, err := c.sinks()
+	if err != nil {
+		return err
+	}
+	redactor, err := c.redactor()
+	if err != nil {
+		return err
+	}
+	if _, err := c.Gorm.options(); err != nil {
+		return err
+	}
+
 	p.logConf = c
+	log.SetRedactor(redactor)
+	log.SetRequestLogOptions(c.requestLogOptions())
 	log.InitLogger(log.Config{
-		EnableTerminalOutput: p.logConf.EnableTerminalOutput,
-		EnableGormOutput:     p.logConf.EnableGormOutput,
+		DefaultConf: &log.DefaultConf{
+			LogLevel:         level,
+			Dir:              c.Dir,
+			File:             c.File,
+			MaxSize:          c.MaxSize,
+			MaxBackups:       c.MaxBackups,
+			MaxAge:           c.MaxAge,
+			Compress:         c.Compress,
+			ReportCaller:     c.ReportCaller,
+			EnableFileOutput: c.EnableFileOutput,
+			StackFilter:      c.StackFilter,
+			BufferSize:       c.BufferSize,
+			Overflow:         c.Overflow,
+			SampleRate:       c.SampleRate,
+		},
+		EnableTerminalOutput: c.EnableTerminalOutput,
+		EnableGormOutput:     c.EnableGormOutput,
+		Sinks:                sinks,
 	})
+	log.SetModuleLevels(modules)
+	return nil
+}
+
+// Reload 热更新 level 与 module_levels，不重新创建日志输出
+func (c *LogConf) Reload(p *parser) error {
+	level, modules, err := c.levels()
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+	log.SetModuleLevels(modules)
+	if p.logConf != nil {
+		p.logConf.Level, p.logConf.ModuleLevels = c.Level, c.ModuleLevels
+	}
 	return nil
 }
 
+func (c *LogConf) levels() (logrus.Level, map[string]logrus.Level, error) {
+	level := logrus.DebugLevel
+	if c.Level != "" {
+		parsed, err := logrus.ParseLevel(c.Level)
+		if err != nil {
+			return level, nil, fmt.Errorf("log config level: %w", err)
+		}
+		level = parsed
+	}
+
+	modules := make(map[string]logrus.Level, len(c.ModuleLevels))
+	for module, name := range c.ModuleLevels {
+		parsed, err := logrus.ParseLevel(name)
+		if err != nil {
+			return level, nil, fmt.Errorf("log config module %s level: %w", module, err)
+		}
+		modules[module] = parsed
+	}
+	return level, modules, nil
+}
+
+func (c *LogConf) sinks() ([]log.SinkConfig, error) {
+	sinks := make([]log.SinkConfig, 0, len(c.Sinks))
+	for _, s := range c.Sinks {
+		level := logrus.TraceLevel
+		if s.Level != "" {
+			parsed, err := logrus.ParseLevel(s.Level)
+			if err != nil {
+				return nil, fmt.Errorf("log config sink %s level: %w", s.Type, err)
+			}
+			level = parsed
+		}
+		sinks = append(sinks, log.SinkConfig{
+			Type:       s.Type,
+			Name:       s.Name,
+			Level:      level,
+			Format:     s.Format,
+			BufferSize: s.BufferSize,
+			Overflow:   s.Overflow,
+			SampleRate: s.SampleRate,
+			Dir:        s.Dir,
+			File:       s.File,
+			MaxSize:    s.MaxSize,
+			MaxBackups: s.MaxBackups,
+			MaxAge:     s.MaxAge,
+			Compress:   s.Compress,
+			Network:    s.Network,
+			Address:    s.Address,
+			Tag:        s.Tag,
+			URL:        s.Url,
+			Headers:    s.Headers,
+			BatchSize:  s.BatchSize,
+			FlushEvery: time.Duration(s.FlushInterval) * time.Second,
+			Timeout:    time.Duration(s.Timeout) * time.Second,
+		})
+	}
+	return sinks, nil
+}
+
+func (c *LogConf) requestLogOptions() log.RequestLogOptions {
+	opts := log.DefaultRequestLogOptions()
+	if c.Request.ExcludePaths != nil {
+		opts.ExcludePaths = c.Request.ExcludePaths
+	}
+	if c.Request.MaxBodySize != 0 {
+		opts.MaxBodySize = c.Request.MaxBodySize
+	}
+	if len(c.Request.BodyContentTypes) > 0 {
+		opts.BodyContentTypes = c.Request.BodyContentTypes
+	}
+	if c.Request.SuccessSampleRate != nil {
+		opts.SuccessSampleRate = *c.Request.SuccessSampleRate
+	}
+	if c.Request.SlowThreshold > 0 {
+		opts.SlowThreshold = time.Duration(c.Request.SlowThreshold) * time.Millisecond
+	}
+	if c.Request.MaxParamsSize > 0 {
+		opts.MaxParamsSize = c.Request.MaxParamsSize
+	}
+	if c.Request.Headers != nil {
+		opts.Headers = c.Request.Headers
+	}
+	return opts
+}
+
+func (c *LogConf) redactor() (*log.Redactor, error) {
+	var rules []log.RedactRule
+	if !c.Redact.DisableDefault {
+		rules = append(rules, log.DefaultRedactRules...)
+	}
+	for _, r := range c.Redact.Rules {
+		rules = append(rules, log.RedactRule{Fields: r.Fields, Paths: r.Paths, Pattern: r.Pattern, Luhn: r.Luhn, Mask: r.Mask})
+	}
+	return log.NewRedactor(rules)
+}
+
+func (c LogGormConf) options() ([]log.GormOption, error) {
+	opts := []log.GormOption{
+		log.SetGormIgnoreRecordNotFound(c.IgnoreRecordNotFound),
+		log.SetGormParameterizedQueries(c.ParameterizedQueries),
+	}
+	switch strings.ToLower(c.Level) {
+	case "":
+	case "silent":
+		opts = append(opts, log.SetGormLogLevel(gLog.Silent))
+	case "error":
+		opts = append(opts, log.SetGormLogLevel(gLog.Error))
+	case "warn":
+		opts = append(opts, log.SetGormLogLevel(gLog.Warn))
+	case "info":
+		opts = append(opts, log.SetGormLogLevel(gLog.Info))
+	default:
+		return nil, fmt.Errorf("log config gorm level %q: want silent/error/warn/info", c.Level)
+	}
+	if c.SlowThreshold != 0 {
+		opts = append(opts, log.SetGormSlowThreshold(time.Duration(c.SlowThreshold)*time.Millisecond))
+	}
+	return opts, nil
+}
+
 func (c *LogConf) Destroy() error {
+	log.CloseSinks()
+	return nil
+}
+
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+
+	RateLimitKeyByIP     = "ip"
+	RateLimitKeyByUser   = "user"
+	RateLimitKeyByHeader = "header:"
+)
+
+func (c *RateLimitConf) Initialize(inConfig bool, p *parser) error {
+	p.rateLimitConf = c
+	if c.Backend == "" {
+		c.Backend = RateLimitBackendMemory
+	}
+	if c.Rate == 0 {
+		c.Rate = 10
+	}
+	if c.Burst == 0 {
+		c.Burst = 15
+	}
+	if c.Rate < 0 || c.Burst < 0 {
+		return errors.New("rate_limit rate and burst must be positive")
+	}
+	// 请求头由客户端控制，任何人都能选择最高的限额
+	if c.TierHeader != "" {
+		return errors.New("rate_limit tier_header is not supported, the tier must be set by the auth middleware")
+	}
+
+	for i, policy := range c.Policies {
+		if policy.Name == "" {
+			c.Policies[i].Name = fmt.Sprintf("policy_%d", i)
+		}
+		if policy.KeyBy == "" {
+			c.Policies[i].KeyBy = RateLimitKeyByIP
+		}
+		if policy.Rate <= 0 || policy.Burst <= 0 {
+			return fmt.Errorf("rate_limit policy [%s] requires rate and burst", c.Policies[i].Name)
+		}
+		for tier, quota := range policy.Tiers {
+			if quota.Rate <= 0 || quota.Burst <= 0 {
+				return fmt.Errorf("rate_limit policy [%s] tier [%s] requires rate and burst", c.Policies[i].Name, tier)
+			}
+		}
+	}
+
+	switch c.Backend {
+	case RateLimitBackendMemory:
+	case RateLimitBackendRedis:
+		if c.RedisIns == "" {
+			return errors.New("rate_limit redis backend requires redis_ins")
+		}
+	default:
+		return fmt.Errorf("rate_limit backend not support:%s", c.Backend)
+	}
+	return nil
+}
+
+func (c *RateLimitConf) Destroy() error {
+	return nil
+}
+
+// AnchorOriginRegex 为 allow_origin_regex 加上 ^...$，避免 example\.com 匹配到 example.com.attacker.net
+func AnchorOriginRegex(expr string) string {
+	return "^(?:" + expr + ")$"
+}
+
+func (c *CorsConf) Initialize(inConfig bool, p *parser) error {
+	if !inConfig {
+		return nil
+	}
+	p.corsConf = c
+
+	for _, expr := range c.AllowOriginRegex {
+		if _, err := regexp.Compile(AnchorOriginRegex(expr)); err != nil {
+			return fmt.Errorf("cors allow_origin_regex invalid:%s, %w", expr, err)
+		}
+	}
+	if c.AllowCredentials {
+		for _, origin := range c.AllowOrigins {
+			if origin == "*" {
+				return errors.New("cors allow_origins \"*\" cannot be combined with allow_credentials")
+			}
+		}
+	}
+	if len(c.AllowMethods) == 0 {
+		c.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	}
+	if c.MaxAge == 0 {
+		c.MaxAge = 600
+	}
+	return nil
+}
+
+func (c *CorsConf) Destroy() error {
+	return nil
+}
+
+func (c *RequestLimitConf) Initialize(inConfig bool, p *parser) error {
+	p.requestLimitConf = c
+	if c.MaxBodySize == 0 {
+		c.MaxBodySize = 10 << 20
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30000
+	}
+	return nil
+}
+
+func (c *RequestLimitConf) Destroy() error {
+	return nil
+}
+
+func (c *AuthConf) Initialize(inConfig bool, p *parser) error {
+	p.authConf = c
+	if c.Algorithm == "" {
+		c.Algorithm = "HS256"
+	}
+	if c.Header == "" {
+		c.Header = "Authorization"
+	}
+	if c.Scheme == "" {
+		c.Scheme = "Bearer"
+	}
+	if !inConfig {
+		return nil
+	}
+
+	switch c.Algorithm {
+	case "HS256":
+		if p.systemConf == nil || p.systemConf.AuthSecret == "" {
+			return errors.New("auth HS256 requires system auth_secret")
+		}
+	case "RS256", "ES256":
+		if c.JwksFile == "" {
+			return fmt.Errorf("auth %s requires jwks_file", c.Algorithm)
+		}
+	default:
+		return fmt.Errorf("auth algorithm not support:%s", c.Algorithm)
+	}
+	return nil
+}
+
+func (c *AuthConf) Destroy() error {
+	return nil
+}
+
+const (
+	RbacSourceConfig = "config"
+	RbacSourceDB     = "db"
+)
+
+func (c *RbacConf) Initialize(inConfig bool, p *parser) error {
+	if !inConfig {
+		return nil
+	}
+	p.rbacConf = c
+
+	if c.Source == "" {
+		c.Source = RbacSourceConfig
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = 60
+	}
+	switch c.Source {
+	case RbacSourceConfig:
+	case RbacSourceDB:
+		if c.MysqlIns == "" {
+			return errors.New("rbac db source requires mysql_ins")
+		}
+		if c.Table == "" {
+			c.Table = "rbac_policy"
+		}
+	default:
+		return fmt.Errorf("rbac source not support:%s", c.Source)
+	}
+	return nil
+}
+
+func (c *RbacConf) Destroy() error {
+	return nil
+}
+
+// 验签 nonce 的存放位置
+const (
+	NonceBackendMemory = "memory"
+	NonceBackendRedis  = "redis"
+)
+
+func (c *SignConf) Initialize(inConfig bool, p *parser) error {
+	if !inConfig {
+		return nil
+	}
+	p.signConf = c
+
+	if c.Expire == 0 {
+		c.Expire = 300
+	}
+	if c.NonceBackend == "" {
+		c.NonceBackend = NonceBackendMemory
+	}
+	switch c.NonceBackend {
+	case NonceBackendMemory:
+	case NonceBackendRedis:
+		if c.RedisIns == "" {
+			return errors.New("sign redis nonce backend requires redis_ins")
+		}
+	default:
+		return fmt.Errorf("sign nonce_backend not support:%s", c.NonceBackend)
+	}
+
+	if c.SignOutbound {
+		if c.AppId == "" || c.Secret == "" {
+			return errors.New("sign outbound requires app_id and secret")
+		}
+		// httpClient 需先于 sign 初始化，否则出站请求不会被签名
+		if p.httpClientConf == nil {
+			return errors.New("sign outbound requires http client config")
+		}
+		p.httpClient = p.httpClientConf.Connect(signature.NewSigner(c.AppId, c.Secret).Sign)
+	}
+	return nil
+}
+
+func (c *SignConf) Destroy() error {
+	return nil
+}
+
+func (c *IdempotencyConf) Initialize(inConfig bool, p *parser) error {
+	if !inConfig {
+		return nil
+	}
+	p.idempotencyConf = c
+
+	if c.RedisIns == "" {
+		return errors.New("idempotency requires redis_ins")
+	}
+	if c.Header == "" {
+		c.Header = "Idempotency-Key"
+	}
+	if c.TTL == 0 {
+		c.TTL = 86400
+	}
+	if c.LockTTL == 0 {
+		c.LockTTL = 60
+	}
+	return nil
+}
+
+func (c *IdempotencyConf) Destroy() error {
 	return nil
 }
 
+func (c *TracingConf) Initialize(inConfig bool, p *parser) error {
+	if !inConfig {
+		return nil
+	}
+	p.tracingConf = c
+
+	if c.Exporter == "" {
+		c.Exporter = tracing.ExporterNone
+	}
+	if c.SampleRatio <= 0 || c.SampleRatio > 1 {
+		c.SampleRatio = 1
+	}
+	if c.Exporter == tracing.ExporterOTLP && c.Endpoint == "" {
+		return errors.New("tracing otlp exporter requires endpoint")
+	}
+
+	opts := tracing.Options{
+		Env:         p.env,
+		Exporter:    c.Exporter,
+		Endpoint:    c.Endpoint,
+		Insecure:    c.Insecure,
+		Headers:     c.Headers,
+		File:        c.File,
+		SampleRatio: c.SampleRatio,
+	}
+	if p.systemConf != nil {
+		opts.ServiceName = p.systemConf.ServiceName
+		opts.Version = p.systemConf.Version
+	}
+	return tracing.Init(opts)
+}
+
+func (c *TracingConf) Destroy() error {
+	return tracing.Shutdown(context.Background())
+}
+
+func (c *RequestIdConf) Initialize(inConfig bool, p *parser) error {
+	p.requestIdConf = c
+	if len(c.Headers) == 0 {
+		c.Headers = []string{"X-Request-Id", "traceparent"}
+	}
+	if c.Pattern == "" {
+		c.Pattern = `^[A-Za-z0-9._:-]{8,128}$`
+	}
+	if _, err := regexp.Compile(c.Pattern); err != nil {
+		return fmt.Errorf("request_id pattern invalid:%s, %w", c.Pattern, err)
+	}
+	if c.ResponseHeader == "" {
+		c.ResponseHeader = "X-Request-Id"
+	}
+	return nil
+}
+
+func (c *RequestIdConf) Destroy() error {
+	return nil
+}
+
+func (c *AlertConf) Initialize(inConfig bool, p *parser) error {
+	if !inConfig {
+		return nil
+	}
+	p.alertConf = c
+
+	senders := make([]alert.Sender, 0, len(c.Senders))
+	for _, s := range c.Senders {
+		sender, err := alert.NewSender(alert.SenderConfig{
+			Type:    s.Type,
+			Name:    s.Name,
+			URL:     s.Url,
+			Secret:  s.Secret,
+			Headers: s.Headers,
+		})
+		if err != nil {
+			return err
+		}
+		senders = append(senders, sender)
+	}
+
+	rules := make([]alert.Rule, 0, len(c.Rules))
+	for _, r := range c.Rules {
+		level := logrus.ErrorLevel
+		if r.Level != "" {
+			parsed, err := logrus.ParseLevel(r.Level)
+			if err != nil {
+				return fmt.Errorf("alert rule %s level: %w", r.Name, err)
+			}
+			level = parsed
+		}
+		rules = append(rules, alert.Rule{
+			Name:      r.Name,
+			Level:     level,
+			Keywords:  r.Keywords,
+			Classes:   r.Classes,
+			Threshold: r.Threshold,
+		})
+	}
+
+	hook, err := alert.New(alert.Options{
+		Rules:         rules,
+		Window:        time.Duration(c.Window) * time.Second,
+		Silence:       time.Duration(c.Silence) * time.Second,
+		RatePerMinute: c.RatePerMinute,
+		Timeout:       time.Duration(c.Timeout) * time.Second,
+	}, senders...)
+	if err != nil {
+		return err
+	}
+	c.hook = hook
+	log.AddHook(hook)
+	return nil
+}
+
+func (c *AlertConf) Destroy() error {
+	if c.hook == nil {
+		return nil
+	}
+	log.RemoveHook(c.hook)
+	return c.hook.Close()
+}
+
 func (c *MysqlList) Initialize(inConfig bool, p *parser) error {
 	if !inConfig {
 		return nil
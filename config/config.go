@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gomodule/redigo/redis"
+	"github.com/hyzx-go/common-b2c/alert"
 	"github.com/hyzx-go/common-b2c/log"
 	"github.com/hyzx-go/common-b2c/rpc"
+	"github.com/hyzx-go/common-b2c/tracing"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"net"
@@ -60,7 +62,218 @@ type LogConf struct {
 	EnableTerminalOutput bool   `mapstructure:"enable_terminal_output" json:"enableTerminalOutput" yaml:"enable_terminal_output"`
 	EnableFileOutput     bool   `mapstructure:"enable_file_output" json:"enableFileOutput" yaml:"enable_file_output"`
 	EnableGormOutput     bool   `mapstructure:"enable_gorm.output" json:"enableGormOutput" yaml:"enable_gorm.output"`
+	Level                string `mapstructure:"level" json:"level" yaml:"level"`                        // trace/debug/info/warn/error，默认 debug
+	MaxSize              int    `mapstructure:"max_size" json:"maxSize" yaml:"max_size"`                // 单个日志文件大小（MB），默认 10
+	MaxBackups           int    `mapstructure:"max_backups" json:"maxBackups" yaml:"max_backups"`       // 保留的旧文件个数，默认 5
+	MaxAge               int    `mapstructure:"max_age" json:"maxAge" yaml:"max_age"`                   // 旧文件保留天数，默认 30
+	Compress             bool   `mapstructure:"compress" json:"compress" yaml:"compress"`               // 是否压缩旧文件
+	ReportCaller         bool   `mapstructure:"report_caller" json:"reportCaller" yaml:"report_caller"` // 是否输出调用位置
+	// ModuleLevels 模块级别，配合 log.Ctx(ctx).Module(name) 使用，支持配置热更新
+	ModuleLevels map[string]string `mapstructure:"module_levels" json:"moduleLevels" yaml:"module_levels"`
+	// AdminToken 日志级别管理接口的 token，为空时不注册接口
+	AdminToken string `mapstructure:"admin_token" json:"adminToken" yaml:"admin_token"`
+	AdminPath  string `mapstructure:"admin_path" json:"adminPath" yaml:"admin_path"` // 默认 /admin/log/level
+	// 文件输出的异步队列：buffer_size 默认 4096，小于 0 时同步写入；overflow 为 block/drop/sample
+	BufferSize int    `mapstructure:"buffer_size" json:"bufferSize" yaml:"buffer_size"`
+	Overflow   string `mapstructure:"overflow" json:"overflow" yaml:"overflow"`
+	SampleRate int    `mapstructure:"sample_rate" json:"sampleRate" yaml:"sample_rate"`
+	// Sinks 附加的日志输出：stdout/file/syslog/http
+	Sinks []LogSinkConf `mapstructure:"sinks" json:"sinks" yaml:"sinks"`
+	// Redact 日志脱敏规则
+	Redact LogRedactConf `mapstructure:"redact" json:"redact" yaml:"redact"`
+	// Request 请求日志的记录范围
+	Request LogRequestConf `mapstructure:"request" json:"request" yaml:"request"`
+	// Gorm SQL 日志，enable_gorm.output 开启时生效
+	Gorm LogGormConf `mapstructure:"gorm" json:"gorm" yaml:"gorm"`
 }
+
+type LogGormConf struct {
+	Level                string `mapstructure:"level" json:"level" yaml:"level"`                                                    // silent/error/warn/info，默认 warn
+	SlowThreshold        int    `mapstructure:"slow_threshold" json:"slowThreshold" yaml:"slow_threshold"`                          // 毫秒，默认 200，小于 0 关闭
+	IgnoreRecordNotFound bool   `mapstructure:"ignore_record_not_found" json:"ignoreRecordNotFound" yaml:"ignore_record_not_found"` // 不记录 record not found
+	ParameterizedQueries bool   `mapstructure:"parameterized_queries" json:"parameterizedQueries" yaml:"parameterized_queries"`     // 输出带 ? 的 SQL，不拼接参数
+}
+
+type LogRequestConf struct {
+	ExcludePaths      []string `mapstructure:"exclude_paths" json:"excludePaths" yaml:"exclude_paths"`                  // 默认 /metrics，以 * 结尾时按前缀匹配
+	MaxBodySize       int      `mapstructure:"max_body_size" json:"maxBodySize" yaml:"max_body_size"`                   // 字节，默认 4096，小于 0 不记录响应体
+	BodyContentTypes  []string `mapstructure:"body_content_types" json:"bodyContentTypes" yaml:"body_content_types"`    // 默认 JSON/文本
+	SuccessSampleRate *float64 `mapstructure:"success_sample_rate" json:"successSampleRate" yaml:"success_sample_rate"` // 0~1，默认 1
+	SlowThreshold     int      `mapstructure:"slow_threshold" json:"slowThreshold" yaml:"slow_threshold"`               // 毫秒，默认 5000
+	MaxParamsSize     int      `mapstructure:"max_params_size" json:"maxParamsSize" yaml:"max_params_size"`             // 请求体参数读取上限，字节，默认 16KB
+	Headers           []string `mapstructure:"headers" json:"headers" yaml:"headers"`                                   // 记录的请求头
+}
+
+type LogRedactConf struct {
+	DisableDefault bool            `mapstructure:"disable_default" json:"disableDefault" yaml:"disable_default"` // 不使用内置规则
+	Rules          []LogRedactRule `mapstructure:"rules" json:"rules" yaml:"rules"`                              // 追加在内置规则之后
+}
+
+type LogRedactRule struct {
+	Fields  []string `mapstructure:"fields" json:"fields" yaml:"fields"`
+	Paths   []string `mapstructure:"paths" json:"paths" yaml:"paths"` // JSONPath，如 $.user.id_card
+	Pattern string   `mapstructure:"pattern" json:"pattern" yaml:"pattern"`
+	Luhn    bool     `mapstructure:"luhn" json:"luhn" yaml:"luhn"`
+	Mask    string   `mapstructure:"mask" json:"mask" yaml:"mask"` // full/partial/hash
+}
+
+type LogSinkConf struct {
+	Type          string            `mapstructure:"type" json:"type" yaml:"type"` // stdout/file/syslog/http
+	Name          string            `mapstructure:"name" json:"name" yaml:"name"`
+	Level         string            `mapstructure:"level" json:"level" yaml:"level"`                  // 为空时输出全部级别
+	Format        string            `mapstructure:"format" json:"format" yaml:"format"`               // json/logfmt/console
+	BufferSize    int               `mapstructure:"buffer_size" json:"bufferSize" yaml:"buffer_size"` // 异步队列长度，0 为同步写入
+	Overflow      string            `mapstructure:"overflow" json:"overflow" yaml:"overflow"`         // block/drop/sample，默认 block
+	SampleRate    int               `mapstructure:"sample_rate" json:"sampleRate" yaml:"sample_rate"`
+	Dir           string            `mapstructure:"dir" json:"dir" yaml:"dir"`
+	File          string            `mapstructure:"file" json:"file" yaml:"file"`
+	MaxSize       int               `mapstructure:"max_size" json:"maxSize" yaml:"max_size"`
+	MaxBackups    int               `mapstructure:"max_backups" json:"maxBackups" yaml:"max_backups"`
+	MaxAge        int               `mapstructure:"max_age" json:"maxAge" yaml:"max_age"`
+	Compress      bool              `mapstructure:"compress" json:"compress" yaml:"compress"`
+	Network       string            `mapstructure:"network" json:"network" yaml:"network"`
+	Address       string            `mapstructure:"address" json:"address" yaml:"address"`
+	Tag           string            `mapstructure:"tag" json:"tag" yaml:"tag"`
+	Url           string            `mapstructure:"url" json:"url" yaml:"url"`
+	Headers       map[string]string `mapstructure:"headers" json:"headers" yaml:"headers"`
+	BatchSize     int               `mapstructure:"batch_size" json:"batchSize" yaml:"batch_size"`
+	FlushInterval int               `mapstructure:"flush_interval" json:"flushInterval" yaml:"flush_interval"` // 秒
+	Timeout       int               `mapstructure:"timeout" json:"timeout" yaml:"timeout"`                     // 秒
+}
+type RateLimitConf struct {
+	Backend    string            `mapstructure:"backend" json:"backend" yaml:"backend"`
+	RedisIns   string            `mapstructure:"redis_ins" json:"redisIns" yaml:"redis_ins"`
+	Rate       float64           `mapstructure:"rate" json:"rate" yaml:"rate"`
+	Burst      int               `mapstructure:"burst" json:"burst" yaml:"burst"`
+	TierHeader string            `mapstructure:"tier_header" json:"tierHeader" yaml:"tier_header"` // 已废弃：限流等级只取认证中间件写入的值，配置后启动报错
+	Allowlist  []string          `mapstructure:"allowlist" json:"allowlist" yaml:"allowlist"`
+	Policies   []RateLimitPolicy `mapstructure:"policies" json:"policies" yaml:"policies"`
+}
+
+type RateLimitPolicy struct {
+	Name    string                    `mapstructure:"name" json:"name" yaml:"name"`
+	Route   string                    `mapstructure:"route" json:"route" yaml:"route"`
+	Methods []string                  `mapstructure:"methods" json:"methods" yaml:"methods"`
+	KeyBy   string                    `mapstructure:"key_by" json:"keyBy" yaml:"key_by"`
+	Rate    float64                   `mapstructure:"rate" json:"rate" yaml:"rate"`
+	Burst   int                       `mapstructure:"burst" json:"burst" yaml:"burst"`
+	Tiers   map[string]RateLimitQuota `mapstructure:"tiers" json:"tiers" yaml:"tiers"`
+}
+
+type RateLimitQuota struct {
+	Rate  float64 `mapstructure:"rate" json:"rate" yaml:"rate"`
+	Burst int     `mapstructure:"burst" json:"burst" yaml:"burst"`
+}
+
+type CorsConf struct {
+	AllowOrigins     []string `mapstructure:"allow_origins" json:"allowOrigins" yaml:"allow_origins"`
+	AllowOriginRegex []string `mapstructure:"allow_origin_regex" json:"allowOriginRegex" yaml:"allow_origin_regex"`
+	AllowMethods     []string `mapstructure:"allow_methods" json:"allowMethods" yaml:"allow_methods"`
+	AllowHeaders     []string `mapstructure:"allow_headers" json:"allowHeaders" yaml:"allow_headers"`
+	ExposeHeaders    []string `mapstructure:"expose_headers" json:"exposeHeaders" yaml:"expose_headers"`
+	MaxAge           int      `mapstructure:"max_age" json:"maxAge" yaml:"max_age"`
+	AllowCredentials bool     `mapstructure:"allow_credentials" json:"allowCredentials" yaml:"allow_credentials"`
+}
+
+type RequestLimitConf struct {
+	MaxBodySize int64               `mapstructure:"max_body_size" json:"maxBodySize" yaml:"max_body_size"` // 字节
+	Timeout     int                 `mapstructure:"timeout" json:"timeout" yaml:"timeout"`                 // 毫秒
+	Routes      []RequestLimitRoute `mapstructure:"routes" json:"routes" yaml:"routes"`
+}
+
+type RequestLimitRoute struct {
+	Route       string   `mapstructure:"route" json:"route" yaml:"route"`
+	Methods     []string `mapstructure:"methods" json:"methods" yaml:"methods"`
+	MaxBodySize int64    `mapstructure:"max_body_size" json:"maxBodySize" yaml:"max_body_size"`
+	Timeout     int      `mapstructure:"timeout" json:"timeout" yaml:"timeout"`
+}
+
+type AuthConf struct {
+	Algorithm string   `mapstructure:"algorithm" json:"algorithm" yaml:"algorithm"` // HS256 使用 system.auth_secret，RS256/ES256 使用 jwks_file
+	JwksFile  string   `mapstructure:"jwks_file" json:"jwksFile" yaml:"jwks_file"`
+	Issuer    string   `mapstructure:"issuer" json:"issuer" yaml:"issuer"`
+	Audience  []string `mapstructure:"audience" json:"audience" yaml:"audience"`
+	Leeway    int      `mapstructure:"leeway" json:"leeway" yaml:"leeway"` // 秒
+	Header    string   `mapstructure:"header" json:"header" yaml:"header"`
+	Scheme    string   `mapstructure:"scheme" json:"scheme" yaml:"scheme"`
+}
+
+type RbacConf struct {
+	Source   string       `mapstructure:"source" json:"source" yaml:"source"` // config 或 db
+	MysqlIns string       `mapstructure:"mysql_ins" json:"mysqlIns" yaml:"mysql_ins"`
+	Table    string       `mapstructure:"table" json:"table" yaml:"table"`
+	CacheTTL int          `mapstructure:"cache_ttl" json:"cacheTTL" yaml:"cache_ttl"` // 秒
+	Reload   int          `mapstructure:"reload" json:"reload" yaml:"reload"`         // 秒，db 策略定时重新加载，0 不重新加载
+	Policies []RbacPolicy `mapstructure:"policies" json:"policies" yaml:"policies"`
+}
+
+type RbacPolicy struct {
+	Role        string   `mapstructure:"role" json:"role" yaml:"role"`
+	Permissions []string `mapstructure:"permissions" json:"permissions" yaml:"permissions"` // resource:action，支持 * 通配
+	Inherits    []string `mapstructure:"inherits" json:"inherits" yaml:"inherits"`
+	Condition   string   `mapstructure:"condition" json:"condition" yaml:"condition"` // 已注册的属性条件，为空时无条件授权
+}
+
+type SignConf struct {
+	AppId        string            `mapstructure:"app_id" json:"appId" yaml:"app_id"` // 出站签名使用
+	Secret       string            `mapstructure:"secret" json:"secret" yaml:"secret"`
+	SignOutbound bool              `mapstructure:"sign_outbound" json:"signOutbound" yaml:"sign_outbound"` // 为全局 http client 挂上签名
+	Apps         map[string]string `mapstructure:"apps" json:"apps" yaml:"apps"`                           // 入站验签 app id -> secret
+	Expire       int               `mapstructure:"expire" json:"expire" yaml:"expire"`                     // 秒，允许的时间偏差
+	NonceBackend string            `mapstructure:"nonce_backend" json:"nonceBackend" yaml:"nonce_backend"` // memory 或 redis
+	RedisIns     string            `mapstructure:"redis_ins" json:"redisIns" yaml:"redis_ins"`
+}
+
+type IdempotencyConf struct {
+	RedisIns string `mapstructure:"redis_ins" json:"redisIns" yaml:"redis_ins"`
+	Header   string `mapstructure:"header" json:"header" yaml:"header"`
+	TTL      int    `mapstructure:"ttl" json:"ttl" yaml:"ttl"`                // 秒，已完成响应的保存时间
+	LockTTL  int    `mapstructure:"lock_ttl" json:"lockTTL" yaml:"lock_ttl"`  // 秒，处理中标记的过期时间
+	Required bool   `mapstructure:"required" json:"required" yaml:"required"` // 缺少 header 时返回 400
+}
+
+type TracingConf struct {
+	Exporter    string            `mapstructure:"exporter" json:"exporter" yaml:"exporter"` // otlp、stdout、file 或 none
+	Endpoint    string            `mapstructure:"endpoint" json:"endpoint" yaml:"endpoint"`
+	Insecure    bool              `mapstructure:"insecure" json:"insecure" yaml:"insecure"`
+	Headers     map[string]string `mapstructure:"headers" json:"headers" yaml:"headers"`
+	File        string            `mapstructure:"file" json:"file" yaml:"file"`
+	SampleRatio float64           `mapstructure:"sample_ratio" json:"sampleRatio" yaml:"sample_ratio"`
+}
+
+type RequestIdConf struct {
+	Headers        []string `mapstructure:"headers" json:"headers" yaml:"headers"` // 按顺序读取的入站 header，支持 traceparent
+	Pattern        string   `mapstructure:"pattern" json:"pattern" yaml:"pattern"` // 入站 id 的格式校验
+	ResponseHeader string   `mapstructure:"response_header" json:"responseHeader" yaml:"response_header"`
+}
+
+type AlertConf struct {
+	Window        int               `mapstructure:"window" json:"window" yaml:"window"`                          // 秒，聚合窗口，默认 60
+	Silence       int               `mapstructure:"silence" json:"silence" yaml:"silence"`                       // 秒，同一告警的静默时长，默认 600，小于 0 不静默
+	RatePerMinute int               `mapstructure:"rate_per_minute" json:"ratePerMinute" yaml:"rate_per_minute"` // 每分钟最多发送条数，默认 20
+	Timeout       int               `mapstructure:"timeout" json:"timeout" yaml:"timeout"`                       // 秒，单次发送超时，默认 5
+	Rules         []AlertRuleConf   `mapstructure:"rules" json:"rules" yaml:"rules"`                             // 为空时告警全部 Error 日志与 warn_error
+	Senders       []AlertSenderConf `mapstructure:"senders" json:"senders" yaml:"senders"`
+
+	hook *alert.Hook
+}
+
+type AlertRuleConf struct {
+	Name      string   `mapstructure:"name" json:"name" yaml:"name"`
+	Level     string   `mapstructure:"level" json:"level" yaml:"level"` // 默认 error
+	Keywords  []string `mapstructure:"keywords" json:"keywords" yaml:"keywords"`
+	Classes   []string `mapstructure:"classes" json:"classes" yaml:"classes"` // error/warn_error/warning
+	Threshold int      `mapstructure:"threshold" json:"threshold" yaml:"threshold"`
+}
+
+type AlertSenderConf struct {
+	Type    string            `mapstructure:"type" json:"type" yaml:"type"` // webhook/dingtalk/feishu/slack
+	Name    string            `mapstructure:"name" json:"name" yaml:"name"`
+	Url     string            `mapstructure:"url" json:"url" yaml:"url"`
+	Secret  string            `mapstructure:"secret" json:"secret" yaml:"secret"`    // 钉钉、飞书加签密钥
+	Headers map[string]string `mapstructure:"headers" json:"headers" yaml:"headers"` // webhook 附加请求头
+}
+
 type Mysql struct {
 	InsName     string `mapstructure:"ins_name" json:"insName" yaml:"ins_name"`
 	Address     string `mapstructure:"address" json:"address" yaml:"address"`
@@ -188,7 +401,12 @@ func (c *MysqlList) ConnsMysql() map[string]*gorm.DB {
 
 		opts := &gorm.Config{}
 		if config.Log.EnableGormOutput {
-			opts = &gorm.Config{Logger: log.NewGormLogger()}
+			gormOpts, err := config.Log.Gorm.options()
+			if err != nil {
+				panic("mysqlErr-" + mysqlConfig.Address + "-err:" + err.Error())
+			}
+			gormOpts = append(gormOpts, log.SetGormInstance(mysqlConfig.InsName))
+			opts = &gorm.Config{Logger: log.NewGormLogger(gormOpts...)}
 		}
 
 		client, err := gorm.Open(conf, opts)
@@ -197,6 +415,12 @@ func (c *MysqlList) ConnsMysql() map[string]*gorm.DB {
 			panic("mysqlErr-" + mysqlConfig.Address + "-err:" + err.Error())
 		}
 
+		if tracing.Enabled() {
+			if err := client.Use(tracing.GormPlugin(mysqlConfig.InsName)); err != nil {
+				panic("mysqlErr-" + mysqlConfig.Address + "-err:" + err.Error())
+			}
+		}
+
 		// Get the common database object sql.DB and use the functionality it provides
 		db, err := client.DB()
 
@@ -283,6 +507,38 @@ func GetRedisIns(options ...string) (redis.Conn, error) {
 	return conn, nil
 }
 
+// GetRedisPool 获取 Redis 连接池
+func GetRedisPool(instanceName string) (*redis.Pool, error) {
+	if _parser == nil {
+		return nil, ErrNotFind
+	}
+	poolMap, err := _parser.GetRedisDbMap()
+	if err != nil {
+		return nil, err
+	}
+	pool, ok := poolMap[instanceName]
+	if !ok {
+		return nil, fmt.Errorf("GetRedisPool  instanceName not exist:[%s]", instanceName)
+	}
+	return pool, nil
+}
+
+// GetMysqlDB 获取 Mysql 实例
+func GetMysqlDB(instanceName string) (*gorm.DB, error) {
+	if _parser == nil {
+		return nil, ErrNotFind
+	}
+	dbMap, err := _parser.GetMysqlDnMap()
+	if err != nil {
+		return nil, err
+	}
+	db, ok := dbMap[instanceName]
+	if !ok {
+		return nil, fmt.Errorf("GetMysqlDB  instanceName not exist:[%s]", instanceName)
+	}
+	return db, nil
+}
+
 // 创建 Redis 连接池
 func (conf *RedisConf) newRedisPool() *redis.Pool {
 	return &redis.Pool{
@@ -318,6 +574,9 @@ func (conf *RedisConf) newRedisPool() *redis.Pool {
 				return nil, fmt.Errorf("select db error: %w", err)
 			}
 
+			if tracing.Enabled() {
+				return tracing.WrapRedisConn(conn, conf.InsName), nil
+			}
 			return conn, nil
 		},
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
@@ -327,7 +586,7 @@ func (conf *RedisConf) newRedisPool() *redis.Pool {
 	}
 }
 
-func (h *HttpClientConf) Connect() rpc.Http {
+func (h *HttpClientConf) Connect(hooks ...rpc.RequestHook) rpc.Http {
 	dialer := &net.Dialer{
 		// 建立TCP连接的时间
 		Timeout: time.Duration(h.Dialer.Timeout) * time.Second,
@@ -335,21 +594,26 @@ func (h *HttpClientConf) Connect() rpc.Http {
 		KeepAlive: time.Duration(h.Dialer.KeepAlive) * time.Second,
 	}
 
+	var transport http.RoundTripper = &http.Transport{
+		DialContext:        dialer.DialContext,
+		DisableKeepAlives:  h.DisableKeepAlives,
+		DisableCompression: h.DisableCompression,
+		// 所有host的连接池最大连接数量
+		MaxIdleConns: h.MaxIdleConns,
+		// 每个host的连接池最大空闲连接数
+		MaxIdleConnsPerHost: h.MaxIdleConnsPerHost,
+		// 空闲连接在连接池中保留多长时间
+		IdleConnTimeout: time.Duration(h.IdleConnTimeout) * time.Second,
+		// 读取response header的时间,默认 timeout + 5*time.Second
+		ResponseHeaderTimeout: time.Duration(h.ResponseHeaderTimeout) * time.Second,
+	}
+	if tracing.Enabled() {
+		transport = tracing.Transport(transport)
+	}
+
 	return rpc.NewHttpClient(&http.Client{
 		// 设置超时时间
-		Timeout: time.Duration(h.Timeout) * time.Second,
-		Transport: &http.Transport{
-			DialContext:        dialer.DialContext,
-			DisableKeepAlives:  h.DisableKeepAlives,
-			DisableCompression: h.DisableCompression,
-			// 所有host的连接池最大连接数量
-			MaxIdleConns: h.MaxIdleConns,
-			// 每个host的连接池最大空闲连接数
-			MaxIdleConnsPerHost: h.MaxIdleConnsPerHost,
-			// 空闲连接在连接池中保留多长时间
-			IdleConnTimeout: time.Duration(h.IdleConnTimeout) * time.Second,
-			// 读取response header的时间,默认 timeout + 5*time.Second
-			ResponseHeaderTimeout: time.Duration(h.ResponseHeaderTimeout) * time.Second,
-		},
-	})
+		Timeout:   time.Duration(h.Timeout) * time.Second,
+		Transport: transport,
+	}, hooks...)
 }
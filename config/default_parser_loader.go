@@ -75,8 +75,8 @@ func (d *DefaultParserLoader) loadConfig() error {
 
 	// change config
 	d.viper.OnConfigChange(func(e fsnotify.Event) {
-		// Don't do it yet
 		innerLog.GetLogger().Info("On Config Changed", e)
+		d.reloadConfig()
 	})
 
 	// apply config
@@ -87,6 +87,45 @@ func (d *DefaultParserLoader) loadConfig() error {
 	return nil
 }
 
+// reloadable 支持配置热更新的 bean 实现该接口，其余 bean 保持启动时加载的配置
+type reloadable interface {
+	Reload(p *parser) error
+}
+
+func (d *DefaultParserLoader) reloadConfig() {
+	// d.viper 中启动时的配置会覆盖文件内容，变更后的文件读入新的 viper 实例
+	v := viper.New()
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetConfigFile(d.viper.ConfigFileUsed())
+	if err := v.ReadInConfig(); err != nil {
+		innerLog.Ctx(nil).Error("reload config read file", err)
+		return
+	}
+
+	// initConfig 按 beanKeys 的顺序追加 factoryBeans，下标一一对应
+	for i, bean := range d.parser.factoryBeans {
+		if _, ok := bean.(reloadable); !ok {
+			continue
+		}
+		key := d.parser.beanKeys[i]
+		if !v.InConfig(key) {
+			continue
+		}
+
+		fresh := getBeanFactory(key)
+		if err := v.UnmarshalKey(key, fresh); err != nil {
+			innerLog.Ctx(nil).Error(fmt.Sprintf("reload config unmarshal key:%s", key), err)
+			continue
+		}
+		if err := fresh.(reloadable).Reload(d.parser); err != nil {
+			innerLog.Ctx(nil).Error(fmt.Sprintf("reload config key:%s", key), err)
+			continue
+		}
+		innerLog.Ctx(nil).Info(fmt.Sprintf("reload config key:%s successful", key))
+	}
+}
+
 func (d *DefaultParserLoader) initConfig() (err error) {
 	d.parser.initBeanKeys()
 	for _, key := range d.parser.beanKeys {
@@ -32,6 +32,16 @@ type Parser interface {
 	GetAisConf() (*AisConf, error)
 	GetLogConf() (*LogConf, error)
 	GetHttpClientConf() *HttpClientConf
+	GetRateLimitConf() (*RateLimitConf, error)
+	GetCorsConf() (*CorsConf, error)
+	GetRequestLimitConf() (*RequestLimitConf, error)
+	GetAuthConf() (*AuthConf, error)
+	GetRbacConf() (*RbacConf, error)
+	GetSignConf() (*SignConf, error)
+	GetIdempotencyConf() (*IdempotencyConf, error)
+	GetTracingConf() (*TracingConf, error)
+	GetRequestIdConf() (*RequestIdConf, error)
+	GetAlertConf() (*AlertConf, error)
 
 	GetMysqlDnMap() (map[string]*gorm.DB, error)
 	GetRedisDbMap() (map[string]*redis.Pool, error)
@@ -59,13 +69,23 @@ type parser struct {
 	beanKeys []string
 	env      string
 
-	factoryBeans   []BeanFactory
-	systemConf     *SystemConf
-	aisConf        *AisConf
-	logConf        *LogConf
-	mysqlConf      MysqlList
-	redisConf      RedisList
-	httpClientConf *HttpClientConf
+	factoryBeans     []BeanFactory
+	systemConf       *SystemConf
+	aisConf          *AisConf
+	logConf          *LogConf
+	mysqlConf        MysqlList
+	redisConf        RedisList
+	httpClientConf   *HttpClientConf
+	rateLimitConf    *RateLimitConf
+	corsConf         *CorsConf
+	requestLimitConf *RequestLimitConf
+	authConf         *AuthConf
+	rbacConf         *RbacConf
+	signConf         *SignConf
+	idempotencyConf  *IdempotencyConf
+	tracingConf      *TracingConf
+	requestIdConf    *RequestIdConf
+	alertConf        *AlertConf
 }
 
 func (p *parser) GetHTTPClient() rpc.Http {
@@ -120,6 +140,76 @@ func (p *parser) GetLogConf() (*LogConf, error) {
 	return p.logConf, nil
 }
 
+func (p *parser) GetRateLimitConf() (*RateLimitConf, error) {
+	if p == nil || p.rateLimitConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.rateLimitConf, nil
+}
+
+func (p *parser) GetCorsConf() (*CorsConf, error) {
+	if p == nil || p.corsConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.corsConf, nil
+}
+
+func (p *parser) GetRequestLimitConf() (*RequestLimitConf, error) {
+	if p == nil || p.requestLimitConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.requestLimitConf, nil
+}
+
+func (p *parser) GetAuthConf() (*AuthConf, error) {
+	if p == nil || p.authConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.authConf, nil
+}
+
+func (p *parser) GetRbacConf() (*RbacConf, error) {
+	if p == nil || p.rbacConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.rbacConf, nil
+}
+
+func (p *parser) GetSignConf() (*SignConf, error) {
+	if p == nil || p.signConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.signConf, nil
+}
+
+func (p *parser) GetIdempotencyConf() (*IdempotencyConf, error) {
+	if p == nil || p.idempotencyConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.idempotencyConf, nil
+}
+
+func (p *parser) GetTracingConf() (*TracingConf, error) {
+	if p == nil || p.tracingConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.tracingConf, nil
+}
+
+func (p *parser) GetRequestIdConf() (*RequestIdConf, error) {
+	if p == nil || p.requestIdConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.requestIdConf, nil
+}
+
+func (p *parser) GetAlertConf() (*AlertConf, error) {
+	if p == nil || p.alertConf == nil {
+		return nil, ErrNotFind
+	}
+	return p.alertConf, nil
+}
+
 func (p *parser) GetParserManager() *ParserManager {
 	return _parserManager
 }
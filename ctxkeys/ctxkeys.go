@@ -0,0 +1,103 @@
+package ctxkeys
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GinTraceIdKey gin.Context 中保存 trace-id 的 key，同时兼容旧代码中以字符串为 key 的 context
+const GinTraceIdKey = "trace-id"
+
+type traceIdKey struct{}
+
+// WithTraceId 将 trace-id 写入 context
+func WithTraceId(ctx context.Context, traceId string) context.Context {
+	return context.WithValue(ctx, traceIdKey{}, traceId)
+}
+
+// SetTraceId 将 trace-id 同时写入 gin.Context 与 c.Request.Context()
+func SetTraceId(c *gin.Context, traceId string) {
+	c.Set(GinTraceIdKey, traceId)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(WithTraceId(c.Request.Context(), traceId))
+	}
+}
+
+// TraceId 读取 trace-id，*gin.Context 与普通 context.Context 均可。
+// 依次查找 gin.Context、类型化 key、旧的字符串 key、链路追踪 span，都没有时返回空串。
+func TraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if traceId := c.GetString(GinTraceIdKey); traceId != "" {
+			return traceId
+		}
+		if c.Request == nil {
+			return ""
+		}
+		ctx = c.Request.Context()
+	}
+	if traceId, ok := ctx.Value(traceIdKey{}).(string); ok && traceId != "" {
+		return traceId
+	}
+	if traceId, ok := ctx.Value(GinTraceIdKey).(string); ok && traceId != "" {
+		return traceId
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// GinLogFieldsKey gin.Context 中保存请求级日志字段的 key
+const GinLogFieldsKey = "log-fields"
+
+type logFieldsKey struct{}
+
+// WithLogFields 追加请求级日志字段，log.Ctx 打印的每条日志都会带上
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, mergeFields(LogFields(ctx), fields))
+}
+
+// SetLogFields 将日志字段同时追加到 gin.Context 与 c.Request.Context()
+func SetLogFields(c *gin.Context, fields map[string]interface{}) {
+	merged := mergeFields(LogFields(c), fields)
+	c.Set(GinLogFieldsKey, merged)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), logFieldsKey{}, merged))
+	}
+}
+
+// LogFields 读取请求级日志字段，返回值只读
+func LogFields(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if v, exists := c.Get(GinLogFieldsKey); exists {
+			fields, _ := v.(map[string]interface{})
+			return fields
+		}
+		if c.Request == nil {
+			return nil
+		}
+		ctx = c.Request.Context()
+	}
+	fields, _ := ctx.Value(logFieldsKey{}).(map[string]interface{})
+	return fields
+}
+
+// mergeFields 复制后合并，已保存在 context 中的 map 不会被修改
+func mergeFields(base, fields map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(fields))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,34 @@
+package ctxkeys
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTraceId(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	if TraceId(c) != "" || TraceId(context.Background()) != "" {
+		t.Fatal("trace-id should be empty")
+	}
+
+	SetTraceId(c, "t1")
+	if TraceId(c) != "t1" || TraceId(c.Request.Context()) != "t1" {
+		t.Fatalf("gin=%q request=%q", TraceId(c), TraceId(c.Request.Context()))
+	}
+
+	// 由 gin.Context 派生的 context 与旧的字符串 key 仍可读取
+	derived, cancel := context.WithCancel(c)
+	defer cancel()
+	if TraceId(derived) != "t1" {
+		t.Fatalf("derived=%q", TraceId(derived))
+	}
+	//nolint:staticcheck
+	legacy := context.WithValue(context.Background(), GinTraceIdKey, "t2")
+	if TraceId(legacy) != "t2" {
+		t.Fatalf("legacy=%q", TraceId(legacy))
+	}
+}
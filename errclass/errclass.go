@@ -0,0 +1,184 @@
+package errclass
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Severity 错误的严重程度，与 logrus 一致，数值越小越严重，零值为 Error
+type Severity int
+
+const (
+	SeverityError Severity = iota // 记为 Error 并告警
+	SeverityWarn                  // 预期内的错误（唯一键冲突、连接被拒绝等），记为 Warn
+	SeverityInfo                  // 业务上正常的错误，记为 Info
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarn:
+		return "warn"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+// 响应错误码，与 response 包一致（response 包的 TestErrclassCodes 校验）；errclass 不依赖 response，以便 response 使用本包
+const (
+	codeConflict           = 409
+	codeServiceUnavailable = 503
+	codeDatabaseError      = 902
+	codeResourceExists     = 905
+)
+
+// Class 错误分类
+type Class struct {
+	Name     string   // 分类名，写入日志 error_class 字段，告警规则可按分类名匹配
+	Severity Severity // 日志级别
+	Code     int      // 响应错误码（response.ErrorCode），0 时由调用方决定
+}
+
+// Matcher 判断错误是否属于某个分类
+type Matcher interface {
+	Match(err error) bool
+}
+
+// MatcherFunc 函数形式的 Matcher
+type MatcherFunc func(err error) bool
+
+func (f MatcherFunc) Match(err error) bool {
+	return f(err)
+}
+
+// Sentinel 按 errors.Is 匹配
+func Sentinel(target error) Matcher {
+	return MatcherFunc(func(err error) bool {
+		return errors.Is(err, target)
+	})
+}
+
+// Type 按 errors.As 匹配错误类型，如 Type[*net.OpError]()
+func Type[T error]() Matcher {
+	return MatcherFunc(func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	})
+}
+
+// MySQLNumber 按 MySQL 错误号匹配，如 1062 唯一键冲突
+func MySQLNumber(numbers ...uint16) Matcher {
+	return MatcherFunc(func(err error) bool {
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) {
+			return false
+		}
+		for _, number := range numbers {
+			if mysqlErr.Number == number {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Regexp 按错误信息正则匹配
+func Regexp(pattern string) (Matcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("errclass pattern %q: %w", pattern, err)
+	}
+	return MatcherFunc(func(err error) bool {
+		return re.MatchString(err.Error())
+	}), nil
+}
+
+// MustRegexp 同 Regexp，正则不合法时 panic
+func MustRegexp(pattern string) Matcher {
+	m, err := Regexp(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Contains 错误信息包含 substr 时匹配，用于只能拿到错误文本的场景（如驱动错误、日志内容）
+func Contains(substr string) Matcher {
+	return MatcherFunc(func(err error) bool {
+		return strings.Contains(err.Error(), substr)
+	})
+}
+
+// Registry 错误分类注册表，后注册的规则优先匹配，服务可覆盖默认分类
+type Registry struct {
+	mu      sync.RWMutex
+	entries []entry
+}
+
+type entry struct {
+	class    Class
+	matchers []Matcher
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register 注册分类，任一 matcher 命中即属于该分类
+func (r *Registry) Register(class Class, matchers ...Matcher) {
+	if len(matchers) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry{class: class, matchers: matchers})
+}
+
+// Classify 返回错误所属的分类，未注册时返回 false
+func (r *Registry) Classify(err error) (Class, bool) {
+	if err == nil {
+		return Class{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		for _, m := range r.entries[i].matchers {
+			if m.Match(err) {
+				return r.entries[i].class, true
+			}
+		}
+	}
+	return Class{}, false
+}
+
+// Default 全局注册表，log、response、alert 共用
+var Default = NewRegistry()
+
+func init() {
+	Default.Register(Class{Name: "duplicated_key", Severity: SeverityWarn, Code: codeResourceExists},
+		Sentinel(gorm.ErrDuplicatedKey), MySQLNumber(1062))
+	Default.Register(Class{Name: "foreign_key_violated", Severity: SeverityWarn, Code: codeConflict},
+		Sentinel(gorm.ErrForeignKeyViolated), MySQLNumber(1451, 1452))
+	Default.Register(Class{Name: "invalid_db", Severity: SeverityWarn, Code: codeDatabaseError},
+		Sentinel(gorm.ErrInvalidDB), Sentinel(gorm.ErrInvalidTransaction), Sentinel(gorm.ErrMissingWhereClause))
+	Default.Register(Class{Name: "db_unavailable", Severity: SeverityWarn, Code: codeServiceUnavailable},
+		Sentinel(mysql.ErrInvalidConn), Contains("connect: connection refused"), Contains("driver: bad connection"))
+}
+
+// Register 在全局注册表中注册分类
+func Register(class Class, matchers ...Matcher) {
+	Default.Register(class, matchers...)
+}
+
+// Classify 在全局注册表中查找错误分类
+func Classify(err error) (Class, bool) {
+	return Default.Classify(err)
+}
@@ -0,0 +1,77 @@
+package errclass
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestMatchers(t *testing.T) {
+	errStock := errors.New("stock not enough")
+	r := NewRegistry()
+	r.Register(Class{Name: "stock", Severity: SeverityInfo, Code: 20001}, Sentinel(errStock))
+	r.Register(Class{Name: "path", Severity: SeverityWarn}, Type[*fs.PathError]())
+	r.Register(Class{Name: "lock_wait", Severity: SeverityWarn}, MySQLNumber(1205))
+	r.Register(Class{Name: "quota"}, MustRegexp(`quota \d+ exceeded`))
+
+	cases := []struct {
+		err  error
+		name string
+	}{
+		{fmt.Errorf("order 1: %w", errStock), "stock"},
+		{fmt.Errorf("open: %w", &fs.PathError{Op: "open", Path: "a", Err: fs.ErrNotExist}), "path"},
+		{fmt.Errorf("update: %w", &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}), "lock_wait"},
+		{errors.New("user quota 100 exceeded"), "quota"},
+	}
+	for _, c := range cases {
+		class, ok := r.Classify(c.err)
+		if !ok || class.Name != c.name {
+			t.Errorf("Classify(%v) = %v, %v; want %s", c.err, class, ok, c.name)
+		}
+	}
+	if _, ok := r.Classify(errors.New("other")); ok {
+		t.Error("unregistered error classified")
+	}
+	if _, ok := r.Classify(nil); ok {
+		t.Error("nil error classified")
+	}
+	if _, err := Regexp("("); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+func TestLaterRegistrationWins(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Class{Name: "first", Severity: SeverityWarn}, Sentinel(gorm.ErrDuplicatedKey))
+	r.Register(Class{Name: "second", Severity: SeverityError}, Sentinel(gorm.ErrDuplicatedKey))
+	if class, _ := r.Classify(gorm.ErrDuplicatedKey); class.Name != "second" {
+		t.Fatalf("got %s, want second", class.Name)
+	}
+}
+
+func TestDefaultClasses(t *testing.T) {
+	cases := []struct {
+		err  error
+		name string
+		code int
+	}{
+		{gorm.ErrDuplicatedKey, "duplicated_key", 905},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, "duplicated_key", 905},
+		{&mysql.MySQLError{Number: 1452}, "foreign_key_violated", 409},
+		{gorm.ErrMissingWhereClause, "invalid_db", 902},
+		{errors.New("dial tcp 127.0.0.1:3306: connect: connection refused"), "db_unavailable", 503},
+	}
+	for _, c := range cases {
+		class, ok := Classify(c.err)
+		if !ok || class.Name != c.name || class.Code != c.code || class.Severity != SeverityWarn {
+			t.Errorf("Classify(%v) = %+v, %v; want %s/%d", c.err, class, ok, c.name, c.code)
+		}
+	}
+	if _, ok := Classify(gorm.ErrRecordNotFound); ok {
+		t.Error("record not found should stay unclassified")
+	}
+}
@@ -0,0 +1,295 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/utils"
+)
+
+var (
+	// ErrNotObtained 在超时时间内未能获取到锁
+	ErrNotObtained = errors.New("lock: not obtained")
+	// ErrLockNotHeld 锁已过期或被其他持有者占用
+	ErrLockNotHeld = errors.New("lock: not held")
+)
+
+const keyPrefix = "lock:"
+
+// 仅当 value 与持有者 token 一致时才删除，避免误删他人的锁
+var releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// 仅当 value 与持有者 token 一致时才续期
+var refreshScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Options 分布式锁配置
+type Options struct {
+	// TTL 锁的租期
+	TTL time.Duration
+	// RetryInterval 抢锁失败后的重试间隔
+	RetryInterval time.Duration
+	// RenewInterval 自动续期间隔，默认 TTL/3，小于 0 时关闭自动续期
+	RenewInterval time.Duration
+}
+
+type Option func(*Options)
+
+func SetTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = ttl
+	}
+}
+
+func SetRetryInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RetryInterval = interval
+	}
+}
+
+func SetRenewInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RenewInterval = interval
+	}
+}
+
+// Locker 基于 Redis 连接池的分布式锁
+type Locker struct {
+	pool    *redis.Pool
+	options Options
+}
+
+// New 使用指定的连接池创建 Locker
+func New(pool *redis.Pool, opts ...Option) *Locker {
+	options := Options{
+		TTL:           10 * time.Second,
+		RetryInterval: 50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.RenewInterval == 0 {
+		options.RenewInterval = options.TTL / 3
+	}
+	return &Locker{pool: pool, options: options}
+}
+
+// NewWithIns 使用配置中 RedisList 的实例名创建 Locker
+func NewWithIns(insName string, opts ...Option) (*Locker, error) {
+	pool, err := config.GetRedisPool(insName)
+	if err != nil {
+		return nil, err
+	}
+	return New(pool, opts...), nil
+}
+
+// Lock 已获取到的锁
+type Lock struct {
+	locker *Locker
+	key    string
+	token  string
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	lost     chan struct{}
+}
+
+// Key 锁的 key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 持有者 token
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Lost 续期失败（锁已丢失）时关闭
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Obtain 尝试获取一次锁，锁被占用时返回 ErrNotObtained
+func (lk *Locker) Obtain(ctx context.Context, key string) (*Lock, error) {
+	token := utils.MakeUuid()
+	ok, err := lk.acquire(ctx, keyPrefix+key, token)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotObtained
+	}
+	return lk.newLock(keyPrefix+key, token), nil
+}
+
+// TryLock 在 timeout 内循环抢锁，超时返回 ErrNotObtained
+func (lk *Locker) TryLock(ctx context.Context, key string, timeout time.Duration) (*Lock, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	l, err := lk.Lock(ctx, key)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrNotObtained
+	}
+	return l, err
+}
+
+// Lock 阻塞抢锁，直到成功或 ctx 结束
+func (lk *Locker) Lock(ctx context.Context, key string) (*Lock, error) {
+	token := utils.MakeUuid()
+	for {
+		ok, err := lk.acquire(ctx, keyPrefix+key, token)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return lk.newLock(keyPrefix+key, token), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lk.options.RetryInterval):
+		}
+	}
+}
+
+// WithLock 持有锁执行 fn，锁丢失时 fn 收到的 ctx 会被取消
+func (lk *Locker) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	l, err := lk.Lock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := l.Unlock(context.Background()); err != nil && !errors.Is(err, ErrLockNotHeld) {
+			log.Ctx(ctx).Warn("lock release failed", err)
+		}
+	}()
+
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.lost:
+			cancel()
+		case <-fnCtx.Done():
+		}
+	}()
+
+	return fn(fnCtx)
+}
+
+// Unlock 释放锁并停止续期
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.stopRenew()
+
+	conn, err := l.locker.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("lock: get conn: %w", err)
+	}
+	defer conn.Close()
+
+	n, err := redis.Int(releaseScript.DoContext(ctx, conn, l.key, l.token))
+	if err != nil {
+		return fmt.Errorf("lock: release: %w", err)
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Refresh 手动续期
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	conn, err := l.locker.pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("lock: get conn: %w", err)
+	}
+	defer conn.Close()
+
+	n, err := redis.Int(refreshScript.DoContext(ctx, conn, l.key, l.token, ttl.Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("lock: refresh: %w", err)
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (lk *Locker) acquire(ctx context.Context, key, token string) (bool, error) {
+	conn, err := lk.pool.GetContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("lock: get conn: %w", err)
+	}
+	defer conn.Close()
+
+	_, err = redis.String(redis.DoContext(conn, ctx, "SET", key, token, "PX", lk.options.TTL.Milliseconds(), "NX"))
+	if errors.Is(err, redis.ErrNil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("lock: acquire: %w", err)
+	}
+	return true, nil
+}
+
+func (lk *Locker) newLock(key, token string) *Lock {
+	l := &Lock{
+		locker: lk,
+		key:    key,
+		token:  token,
+		stop:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	if lk.options.RenewInterval > 0 {
+		go l.renew()
+	}
+	return l
+}
+
+// renew 在持有期间定时续期，续期失败即认为锁已丢失
+func (l *Lock) renew() {
+	ticker := time.NewTicker(l.locker.options.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			err := l.Refresh(context.Background(), l.locker.options.TTL)
+			if err == nil {
+				continue
+			}
+			if errors.Is(err, ErrLockNotHeld) {
+				log.Ctx(nil).Warn(fmt.Sprintf("lock lost:%s", l.key), err)
+				close(l.lost)
+				return
+			}
+			// 网络抖动等临时错误，下个周期重试
+			log.Ctx(nil).Warn(fmt.Sprintf("lock renew failed:%s", l.key), err)
+		}
+	}
+}
+
+func (l *Lock) stopRenew() {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+}
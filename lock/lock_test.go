@@ -0,0 +1,209 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/hyzx-go/common-b2c/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestLocker(t *testing.T, opts ...Option) (*Locker, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+	return New(pool, opts...), mr
+}
+
+func TestObtainAndUnlock(t *testing.T) {
+	lk, mr := newTestLocker(t, SetRenewInterval(-1))
+	ctx := context.Background()
+
+	l, err := lk.Obtain(ctx, "order:1")
+	if err != nil {
+		t.Fatalf("obtain: %v", err)
+	}
+	if got, _ := mr.Get("lock:order:1"); got != l.Token() {
+		t.Fatalf("token mismatch: %s != %s", got, l.Token())
+	}
+
+	if _, err := lk.Obtain(ctx, "order:1"); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("expected ErrNotObtained, got %v", err)
+	}
+
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if mr.Exists("lock:order:1") {
+		t.Fatal("key should be deleted after unlock")
+	}
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("expected ErrLockNotHeld, got %v", err)
+	}
+}
+
+func TestUnlockByOtherOwner(t *testing.T) {
+	lk, mr := newTestLocker(t, SetRenewInterval(-1))
+	ctx := context.Background()
+
+	l, err := lk.Obtain(ctx, "order:2")
+	if err != nil {
+		t.Fatalf("obtain: %v", err)
+	}
+	// 模拟锁过期后被其他节点抢占
+	mr.Set("lock:order:2", "other-owner")
+
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("expected ErrLockNotHeld, got %v", err)
+	}
+	if got, _ := mr.Get("lock:order:2"); got != "other-owner" {
+		t.Fatalf("lock of other owner must be kept, got %s", got)
+	}
+}
+
+func TestTryLockTimeout(t *testing.T) {
+	lk, _ := newTestLocker(t, SetRenewInterval(-1), SetRetryInterval(10*time.Millisecond))
+	ctx := context.Background()
+
+	l, err := lk.Obtain(ctx, "job")
+	if err != nil {
+		t.Fatalf("obtain: %v", err)
+	}
+	defer l.Unlock(ctx)
+
+	start := time.Now()
+	if _, err := lk.TryLock(ctx, "job", 100*time.Millisecond); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("expected ErrNotObtained, got %v", err)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatal("TryLock returned before timeout")
+	}
+}
+
+func TestTryLockAfterRelease(t *testing.T) {
+	lk, _ := newTestLocker(t, SetRenewInterval(-1), SetRetryInterval(10*time.Millisecond))
+	ctx := context.Background()
+
+	l, err := lk.Obtain(ctx, "job")
+	if err != nil {
+		t.Fatalf("obtain: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.Unlock(ctx)
+	}()
+
+	l2, err := lk.TryLock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	l2.Unlock(ctx)
+}
+
+func TestRenew(t *testing.T) {
+	lk, mr := newTestLocker(t, SetTTL(time.Second), SetRenewInterval(20*time.Millisecond))
+	ctx := context.Background()
+
+	l, err := lk.Obtain(ctx, "renew")
+	if err != nil {
+		t.Fatalf("obtain: %v", err)
+	}
+	defer l.Unlock(ctx)
+
+	// miniredis 不会自动流逝时间，手动推进后等待续期把 TTL 重置回来
+	mr.FastForward(900 * time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for mr.TTL("lock:renew") <= 100*time.Millisecond {
+		if time.Now().After(deadline) {
+			t.Fatal("lock was not renewed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWithLockCancelledWhenLost(t *testing.T) {
+	lk, mr := newTestLocker(t, SetTTL(time.Second), SetRenewInterval(20*time.Millisecond))
+	ctx := context.Background()
+
+	err := lk.WithLock(ctx, "lost", func(ctx context.Context) error {
+		mr.Del("lock:lost")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("ctx was not cancelled")
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	lk, mr := newTestLocker(t)
+	ctx := context.Background()
+
+	called := false
+	err := lk.WithLock(ctx, "with", func(ctx context.Context) error {
+		called = true
+		if !mr.Exists("lock:with") {
+			t.Error("lock should be held inside fn")
+		}
+		return nil
+	})
+	if err != nil || !called {
+		t.Fatalf("WithLock: called=%v err=%v", called, err)
+	}
+	if mr.Exists("lock:with") {
+		t.Fatal("lock should be released after fn")
+	}
+}
+
+func TestLockRedisSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// 与 config.NewRedisPool 一致：启用链路追踪时包装连接，经 pool.Get 取出
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			conn, err := redis.Dial("tcp", mr.Addr())
+			if err != nil {
+				return nil, err
+			}
+			return tracing.WrapRedisConn(conn, "main"), nil
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+	l, err := New(pool).Obtain(ctx, "order:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	names := map[string]bool{}
+	for _, span := range recorder.Ended() {
+		if span.Name() != "request" && span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("span %s not linked to request", span.Name())
+		}
+		names[span.Name()] = true
+	}
+	if !names["redis.SET"] || !(names["redis.EVALSHA"] || names["redis.EVAL"]) {
+		t.Fatalf("redis spans not recorded: %v", names)
+	}
+}
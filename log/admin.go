@@ -0,0 +1,121 @@
+package log
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/response"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AdminTokenHeader 日志管理接口的鉴权请求头
+	AdminTokenHeader = "X-Admin-Token"
+	// DefaultAdminPath 日志管理接口的默认路由
+	DefaultAdminPath = "/admin/log/level"
+
+	defaultDebugTTL = 10 * time.Minute
+	maxDebugTTL     = 24 * time.Hour
+)
+
+// LevelRequest 修改日志级别的请求体，未填写的字段不修改
+type LevelRequest struct {
+	Level      string            `json:"level"`
+	Modules    map[string]string `json:"modules"` // 级别为空串时删除该模块的设置
+	DebugTrace string            `json:"debug_trace"`
+	DebugPath  string            `json:"debug_path"`
+	TTL        int               `json:"ttl"` // 临时 debug 持续秒数，默认 600，最长一天
+	ClearDebug bool              `json:"clear_debug"`
+}
+
+// RegisterAdmin 在 path 注册日志级别管理接口，GET 查看、PUT 修改，token 为空时不注册
+func RegisterAdmin(r gin.IRoutes, path, token string) {
+	if token == "" {
+		return
+	}
+	if path == "" {
+		path = DefaultAdminPath
+	}
+	handler := LevelHandler(token)
+	r.GET(path, handler)
+	r.PUT(path, handler)
+}
+
+// LevelHandler 日志级别管理接口，请求头 X-Admin-Token 需与 token 一致
+func LevelHandler(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader(AdminTokenHeader)), []byte(token)) != 1 {
+			response.FailWithStatus(http.StatusUnauthorized, response.Unauthorized, nil, c)
+			return
+		}
+
+		if c.Request.Method == http.MethodGet {
+			response.OkWithData(Levels(), c)
+			return
+		}
+
+		var req LevelRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.FailWithStatus(http.StatusBadRequest, response.ParamsError, err.Error(), c)
+			return
+		}
+		if err := ApplyLevelRequest(req); err != nil {
+			response.FailWithStatus(http.StatusBadRequest, response.ParamsError, err.Error(), c)
+			return
+		}
+
+		Ctx(c).Warn("log level changed", req)
+		response.OkWithData(Levels(), c)
+	}
+}
+
+// ApplyLevelRequest 校验并应用级别修改，任一级别不合法时不做任何修改
+func ApplyLevelRequest(req LevelRequest) error {
+	var global logrus.Level
+	if req.Level != "" {
+		level, err := logrus.ParseLevel(req.Level)
+		if err != nil {
+			return err
+		}
+		global = level
+	}
+	modules := make(map[string]logrus.Level, len(req.Modules))
+	for module, name := range req.Modules {
+		if name == "" {
+			continue
+		}
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		modules[module] = level
+	}
+
+	ttl := time.Duration(req.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = defaultDebugTTL
+	}
+	if ttl > maxDebugTTL {
+		ttl = maxDebugTTL
+	}
+
+	if req.Level != "" {
+		SetLevel(global)
+	}
+	for module, name := range req.Modules {
+		if name == "" {
+			RemoveModuleLevel(module)
+		} else {
+			SetModuleLevel(module, modules[module])
+		}
+	}
+	if req.ClearDebug {
+		DisableDebugOverrides()
+	}
+	EnableDebugForTrace(req.DebugTrace, ttl)
+	EnableDebugForPath(req.DebugPath, ttl)
+	return nil
+}
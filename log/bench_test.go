@@ -0,0 +1,76 @@
+package log
+
+import (
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// legacyCallStack 旧版逐层调用 runtime.Caller 的实现，用于对比
+func legacyCallStack() []CallerInfo {
+	var callStack []CallerInfo
+	for i := 4; ; i++ {
+		pc, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+		if !strings.Contains(file, "vendor") {
+			callStack = append(callStack, CallerInfo{File: filepath.Dir(file), Function: runtime.FuncForPC(pc).Name(), Line: line})
+		}
+	}
+	return callStack
+}
+
+// atDepth 在 depth 层调用深度下执行 fn，模拟业务代码中的调用栈
+func atDepth(depth int, fn func()) {
+	if depth == 0 {
+		fn()
+		return
+	}
+	atDepth(depth-1, fn)
+}
+
+func BenchmarkCallStackLegacy(b *testing.B) {
+	b.ReportAllocs()
+	atDepth(40, func() {
+		for i := 0; i < b.N; i++ {
+			_ = legacyCallStack()
+		}
+	})
+}
+
+func BenchmarkCallStack(b *testing.B) {
+	f := &OrderedJSONFormatter{}
+	b.ReportAllocs()
+	atDepth(40, func() {
+		for i := 0; i < b.N; i++ {
+			_ = f.captureStack()
+		}
+	})
+}
+
+func benchmarkSink(b *testing.B, bufferSize int) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	sink, err := NewSink(SinkConfig{Type: SinkFile, Dir: b.TempDir(), File: "bench.log"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	hook := newSinkHookWith(SinkConfig{Type: "bench", BufferSize: bufferSize}, sink, &OrderedJSONFormatter{})
+	l.AddHook(hook)
+	defer hook.Close()
+
+	entry := logrus.NewEntry(l).WithFields(logrus.Fields{"trace-id": "bench", "order_id": 42})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entry.Warn("order created")
+	}
+}
+
+func BenchmarkSinkSync(b *testing.B)  { benchmarkSink(b, 0) }
+func BenchmarkSinkAsync(b *testing.B) { benchmarkSink(b, 4096) }
@@ -9,6 +9,7 @@ type Config struct {
 	*DefaultConf
 	EnableTerminalOutput bool
 	EnableGormOutput     bool
+	Sinks                []SinkConfig // 附加的日志输出，与 EnableFileOutput 的文件输出同时生效
 }
 
 type DefaultConf struct {
@@ -20,7 +21,11 @@ type DefaultConf struct {
 	MaxAge           int          // Maximum age of a log file (in days)
 	Compress         bool         // Whether to compress old log files
 	ReportCaller     bool         // Whether to include caller info
-	EnableFileOutput bool
+	EnableFileOutput bool         // Whether to write JSON logs to Dir/File
+	StackFilter      string       // Keep only call stack frames whose file path contains this
+	BufferSize       int          // Async queue size of the file output, 0 uses the default, negative writes synchronously
+	Overflow         string       // Policy when the queue is full: block/drop/sample
+	SampleRate       int          // Keep 1 of SampleRate low level entries under the sample policy
 }
 
 // DefaultConfig returns a default configuration for the logger.
@@ -35,5 +40,7 @@ func DefaultConfig() *DefaultConf {
 		Compress:         true,
 		ReportCaller:     true,
 		EnableFileOutput: true,
+		BufferSize:       4096,
+		Overflow:         OverflowBlock,
 	}
 }
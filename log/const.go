@@ -2,14 +2,21 @@ package log
 
 import (
 	"errors"
+	"github.com/hyzx-go/common-b2c/errclass"
 	"gorm.io/gorm"
 	"strings"
 	"time"
 )
 
+// ErrorClassField 错误分类的日志字段名，值为 errclass.Class 的 Name
+const ErrorClassField = "error_class"
+
+// SlowApiThreshold 默认的慢请求阈值，可通过 log.request.slow_threshold 配置
 const SlowApiThreshold = 5 * time.Second
 
 // 定义需要警告的错误数组
+// 新的错误请通过 errclass.Register 注册，可同时指定级别与响应错误码；该数组仅为兼容保留，
+// errclass 未分类的错误仍按此处 errors.Is 或字符串包含判断
 var WarnErrorSlice = []error{
 	//gorm
 	gorm.ErrInvalidDB,          // 无效的数据库连接
@@ -22,7 +29,14 @@ var WarnErrorSlice = []error{
 	errors.New("driver: bad connection"),
 }
 
+// IsWarnError 判断错误是否只需记为 Warn（或更低）：先按 errclass 分类，未分类时匹配 WarnErrorSlice
 func IsWarnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if class, ok := errclass.Classify(err); ok {
+		return class.Severity != errclass.SeverityError
+	}
 	for _, warnErr := range WarnErrorSlice {
 		// 判断 err 是否和 warnErr 完全匹配，或 err 包含 warnErr 的字符串内容
 		if errors.Is(err, warnErr) || strings.Contains(err.Error(), warnErr.Error()) {
@@ -2,72 +2,220 @@ package log
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	gLog "gorm.io/gorm/logger"
-	"time"
 )
 
+// DefaultSlowThreshold 默认慢 SQL 阈值
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+var gormQueryDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "gorm_query_duration_seconds",
+		Help:    "Duration of SQL queries executed through gorm in seconds",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"instance", "table", "operation", "status"},
+)
+
+func init() {
+	prometheus.MustRegister(gormQueryDuration)
+}
+
+// GormOptions gorm 日志配置
+type GormOptions struct {
+	Instance             string        // 数据库实例名，写入日志与指标
+	LogLevel             gLog.LogLevel // Silent/Error/Warn/Info，默认 Warn
+	SlowThreshold        time.Duration // 慢 SQL 阈值，默认 200ms，小于 0 时关闭
+	IgnoreRecordNotFound bool          // 不把 gorm.ErrRecordNotFound 记为错误
+	ParameterizedQueries bool          // 输出带 ? 占位符的 SQL，不拼接参数
+}
+
+// GormOption gorm 日志配置项
+type GormOption func(*GormOptions)
+
+// SetGormInstance 设置数据库实例名
+func SetGormInstance(name string) GormOption {
+	return func(o *GormOptions) { o.Instance = name }
+}
+
+// SetGormLogLevel 设置日志级别
+func SetGormLogLevel(level gLog.LogLevel) GormOption {
+	return func(o *GormOptions) { o.LogLevel = level }
+}
+
+// SetGormSlowThreshold 设置慢 SQL 阈值
+func SetGormSlowThreshold(threshold time.Duration) GormOption {
+	return func(o *GormOptions) { o.SlowThreshold = threshold }
+}
+
+// SetGormIgnoreRecordNotFound 设置是否忽略 gorm.ErrRecordNotFound
+func SetGormIgnoreRecordNotFound(ignore bool) GormOption {
+	return func(o *GormOptions) { o.IgnoreRecordNotFound = ignore }
+}
+
+// SetGormParameterizedQueries 设置是否输出参数化 SQL
+func SetGormParameterizedQueries(parameterized bool) GormOption {
+	return func(o *GormOptions) { o.ParameterizedQueries = parameterized }
+}
+
 type GormLogger struct {
 	Logger *logrus.Logger
+	opts   GormOptions
 }
 
-// NewGormLogger 创建一个新的 zap 日志记录器
-func NewGormLogger() *GormLogger {
-	return &GormLogger{Logger: logger}
+// NewGormLogger 创建 gorm 日志记录器，输出到全局 logger
+func NewGormLogger(opts ...GormOption) *GormLogger {
+	ensureLogger()
+	options := GormOptions{LogLevel: gLog.Warn, SlowThreshold: DefaultSlowThreshold}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &GormLogger{Logger: logger, opts: options}
 }
 
-// LogMode 实现 gorm.Logger 接口，配置日志级别
+// LogMode 实现 gorm.Logger 接口，返回指定级别的副本，用于 db.Debug() 等场景
 func (z *GormLogger) LogMode(level gLog.LogLevel) gLog.Interface {
-	// LogLevel 设置日志级别
-	return &GormLogger{Logger: z.Logger}
+	l := *z
+	l.opts.LogLevel = level
+	return &l
 }
 
 // Info 实现 gorm.Logger 接口，用于记录普通信息
 func (z *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
-	// 获取 trace ID（例如从 Gin Context 或 ctx 中提取）
-	traceID, ok := ctx.Value("trace-id").(string)
-	if !ok || traceID == "" {
-		traceID = "unknown"
+	if z.opts.LogLevel >= gLog.Info {
+		z.entry(ctx).Infof(msg, args...)
 	}
-
-	// Info 方法
-	z.Logger.WithFields(logrus.Fields{"trace-id": traceID}).Infof(msg, args...)
 }
 
 // Warn 实现 gorm.Logger 接口，用于记录警告信息
 func (z *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
-	traceID, ok := ctx.Value("trace-id").(string)
-	if !ok || traceID == "" {
-		traceID = "unknown"
+	if z.opts.LogLevel >= gLog.Warn {
+		z.entry(ctx).Warnf(msg, args...)
 	}
-	// Warn 方法
-	z.Logger.WithFields(logrus.Fields{"trace-id": traceID}).Warnf(msg, args...)
 }
 
 // Error 实现 gorm.Logger 接口，用于记录错误信息
 func (z *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
-	traceID, ok := ctx.Value("trace-id").(string)
-	if !ok || traceID == "" {
-		traceID = "unknown"
+	if z.opts.LogLevel >= gLog.Error {
+		z.entry(ctx).Errorf(msg, args...)
 	}
-	// Error 方法
-	z.Logger.WithFields(logrus.Fields{"trace-id": traceID}).Errorf(msg, args...)
 }
 
-// Trace 实现 gorm.Logger 接口，用于记录 SQL 执行日志
+// Trace 实现 gorm.Logger 接口，记录 SQL 耗时指标，并按级别输出失败、慢 SQL 与普通 SQL
 func (z *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
 	duration := time.Since(begin)
 	sql, rows := fc()
-	traceID, ok := ctx.Value("trace-id").(string)
-	if !ok || traceID == "" {
-		traceID = "unknown"
+
+	notFound := errors.Is(err, gorm.ErrRecordNotFound)
+	status := "ok"
+	if err != nil && !notFound {
+		status = "error"
+	}
+	table, operation := parseSQL(sql)
+	gormQueryDuration.WithLabelValues(z.opts.Instance, table, operation, status).Observe(duration.Seconds())
+
+	if z.opts.LogLevel <= gLog.Silent {
+		return
+	}
+	fields := logrus.Fields{
+		"caller":   sqlCaller(),
+		"duration": duration.String(),
+		"rows":     rows,
+		"sql":      sql,
+	}
+	entry := z.entry(ctx).WithFields(fields)
+
+	slow := z.opts.SlowThreshold > 0 && duration > z.opts.SlowThreshold
+	switch {
+	case err != nil && z.opts.LogLevel >= gLog.Error && !(notFound && z.opts.IgnoreRecordNotFound):
+		entry = entry.WithField("error", err.Error())
+		level, class := errorLevel([]interface{}{err})
+		if class != "" {
+			entry = entry.WithField(ErrorClassField, class)
+		}
+		entry.Log(level, "SQL query failed")
+	case slow && z.opts.LogLevel >= gLog.Warn:
+		entry.WithField("slow_threshold", z.opts.SlowThreshold.String()).Warn("slow SQL")
+	case z.opts.LogLevel >= gLog.Info:
+		entry.Info("SQL executed")
+	}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter 接口，开启 ParameterizedQueries 时不把参数拼进 SQL
+func (z *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if z.opts.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (z *GormLogger) entry(ctx context.Context) *logrus.Entry {
+	fields := logrus.Fields{"trace-id": traceIdFromContext(ctx)}
+	if z.opts.Instance != "" {
+		fields["db_instance"] = z.opts.Instance
+	}
+	// context 用于按路径过滤临时 debug 规则
+	return z.Logger.WithContext(ctx).WithFields(fields)
+}
+
+// gormLoggerFile 本文件路径，查找 SQL 调用方时跳过
+var gormLoggerFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}()
+
+// sqlCaller 返回执行 SQL 的业务代码位置。
+// gorm 的 utils.FileWithLineNum 只跳过 gorm 源码目录，会停在本文件的 Trace 上，这里同时跳过 gorm.io 下的包与本文件
+func sqlCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != gormLoggerFile && !strings.HasPrefix(frame.Function, "gorm.io/") && !strings.HasSuffix(frame.File, ".gen.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
 	}
-	// 记录 SQL 执行的日志
-	if err != nil {
-		// 错误日志
-		z.Logger.WithFields(logrus.Fields{"trace-id": traceID}).Errorf("SQL query failed: %v | Duration: %v | Rows: %v | Error: %v", sql, duration, rows, err)
+}
+
+var sqlTablePattern = regexp.MustCompile("(?i)\\b(?:from|into|update|join|table)\\s+([`\"\\w.]+)")
+
+// parseSQL 从 SQL 中取出表名与操作类型，用作指标标签
+func parseSQL(sql string) (table, operation string) {
+	sql = strings.TrimSpace(sql)
+	if i := strings.IndexFunc(sql, func(r rune) bool { return r == ' ' || r == '\n' || r == '\t' }); i > 0 {
+		operation = strings.ToLower(sql[:i])
 	} else {
-		// 正常执行日志
-		z.Logger.WithFields(logrus.Fields{"trace-id": traceID}).Infof("SQL executed: %v | Duration: %v | Rows: %v", sql, duration, rows)
+		operation = strings.ToLower(sql)
+	}
+	switch operation {
+	case "select", "insert", "update", "delete", "replace":
+	case "":
+		operation = "unknown"
+	default:
+		operation = "other"
+	}
+
+	table = "unknown"
+	if m := sqlTablePattern.FindStringSubmatch(sql); m != nil {
+		table = m[1]
+		if i := strings.LastIndexByte(table, '.'); i >= 0 {
+			table = table[i+1:]
+		}
+		table = strings.Trim(table, "`\"")
 	}
+	return table, operation
 }
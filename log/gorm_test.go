@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"gorm.io/gorm"
+	gLog "gorm.io/gorm/logger"
+)
+
+func TestGormLoggerTrace(t *testing.T) {
+	buf := captureLogger(t)
+	ctx := ctxkeys.WithTraceId(context.Background(), "trace-sql")
+	sql := func() (string, int64) { return "SELECT * FROM `orders` WHERE id = 1", 1 }
+
+	l := NewGormLogger(SetGormInstance("main"), SetGormSlowThreshold(50*time.Millisecond))
+
+	// 默认 Warn 级别不输出普通 SQL
+	l.Trace(ctx, time.Now(), sql, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("warn level logged fast query: %s", buf.String())
+	}
+
+	l.Trace(ctx, time.Now().Add(-100*time.Millisecond), sql, nil)
+	data := lastLine(t, buf)
+	if data["msg"] != "slow SQL" || data["level"] != "warning" || data["db_instance"] != "main" || data["trace-id"] != "trace-sql" {
+		t.Fatalf("unexpected slow log %v", data)
+	}
+	if caller, _ := data["caller"].(string); !strings.Contains(caller, "gorm_test.go:") {
+		t.Fatalf("caller %q should point at the test", caller)
+	}
+
+	l.Trace(ctx, time.Now(), sql, errors.New("deadlock"))
+	if data = lastLine(t, buf); data["msg"] != "SQL query failed" || data["level"] != "error" || data["error"] != "deadlock" {
+		t.Fatalf("unexpected error log %v", data)
+	}
+
+	// IgnoreRecordNotFound
+	buf.Reset()
+	quiet := NewGormLogger(SetGormIgnoreRecordNotFound(true))
+	quiet.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	if buf.Len() != 0 {
+		t.Fatalf("record not found should be ignored: %s", buf.String())
+	}
+
+	// LogMode 返回副本，不修改原实例
+	l.LogMode(gLog.Info).Trace(ctx, time.Now(), sql, nil)
+	if data = lastLine(t, buf); data["msg"] != "SQL executed" || data["rows"] != float64(1) {
+		t.Fatalf("unexpected info log %v", data)
+	}
+	buf.Reset()
+	l.Trace(ctx, time.Now(), sql, nil)
+	l.LogMode(gLog.Silent).Trace(ctx, time.Now(), sql, errors.New("deadlock"))
+	if buf.Len() != 0 {
+		t.Fatalf("LogMode leaked into parent or silent logged: %s", buf.String())
+	}
+}
+
+func TestGormLoggerParamsFilter(t *testing.T) {
+	params := []interface{}{1, "a"}
+	if _, got := NewGormLogger().ParamsFilter(context.Background(), "SELECT ?", params...); len(got) != 2 {
+		t.Fatalf("interpolated mode dropped params: %v", got)
+	}
+	if _, got := NewGormLogger(SetGormParameterizedQueries(true)).ParamsFilter(context.Background(), "SELECT ?", params...); got != nil {
+		t.Fatalf("parameterized mode kept params: %v", got)
+	}
+}
+
+func TestParseSQL(t *testing.T) {
+	cases := []struct {
+		sql, table, operation string
+	}{
+		{"SELECT * FROM `orders` WHERE id = 1", "orders", "select"},
+		{"INSERT INTO `shop`.`order_items` (`id`) VALUES (1)", "order_items", "insert"},
+		{"UPDATE users SET name = 'a'", "users", "update"},
+		{"delete from carts where id = 1", "carts", "delete"},
+		{"SELECT VERSION()", "unknown", "select"},
+		{"SHOW TABLES", "unknown", "other"},
+	}
+	for _, c := range cases {
+		table, operation := parseSQL(c.sql)
+		if table != c.table || operation != c.operation {
+			t.Errorf("parseSQL(%q) = %s, %s; want %s, %s", c.sql, table, operation, c.table, c.operation)
+		}
+	}
+}
@@ -4,40 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
-	"io"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 )
 
-// JSONAndTextFormatterHook 自定义 Hook，用于同时输出 JSON 和文本格式
-type jSONAndTextFormatterHook struct {
-	JSONWriter io.Writer
-	JSONFormat *OrderedJSONFormatter
-	fields     logrus.Fields
-}
-
-func (hook *jSONAndTextFormatterHook) Fire(entry *logrus.Entry) error {
-	for k, v := range hook.fields {
-		entry.Data[k] = v
-	}
-
-	// 生成 JSON 格式日志
-	jsonData, err := hook.JSONFormat.Format(entry)
-	if err == nil {
-		hook.JSONWriter.Write(jsonData)
-	}
-
-	return nil
-}
-
-func (hook *jSONAndTextFormatterHook) Levels() []logrus.Level {
-	return logrus.AllLevels
-}
-
 // OrderedJSONFormatter 自定义 JSON 格式化器，确保字段顺序
 type OrderedJSONFormatter struct {
 	TimestampFormat string
+	// StackFilter 调用栈只保留文件路径包含该字符串的帧（如项目模块路径），为空时保留除 vendor 外的全部帧
+	StackFilter string
 }
 
 type OrderedLogEntry struct {
@@ -73,25 +50,11 @@ func (f *OrderedJSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 		Extra:   make(map[string]interface{}),
 	}
 
-	// 仅在 err 与 warn 级别打印堆栈信息
-	if entry.Level == logrus.ErrorLevel || entry.Level == logrus.WarnLevel {
-		var callStack []CallerInfo
-		for i := 4; ; i++ { // 从第4层开始，跳过日志库内部调用
-			pc, file, line, ok := runtime.Caller(i)
-			if !ok {
-				break
-			}
-			// 过滤掉不属于本地项目的调用信息
-			if !strings.Contains(file, "vendor") {
-				function := runtime.FuncForPC(pc).Name()
-				callStack = append(callStack, CallerInfo{
-					File:     filepath.Dir(file),
-					Function: function,
-					Line:     line,
-				})
-			}
-		}
-		logEntry.CallStack = callStack
+	// 仅在 err 与 warn 级别打印堆栈信息，异步写出时由 sinkHook 在调用方 goroutine 中提前采集
+	if stack, ok := entry.Data[callStackField].([]CallerInfo); ok {
+		logEntry.CallStack = stack
+	} else if needStack(entry.Level) {
+		logEntry.CallStack = f.captureStack()
 	}
 
 	// 动态设置字段
@@ -113,6 +76,8 @@ func (f *OrderedJSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 			logEntry.Latency = value
 		case "message":
 			logEntry.Message = value
+		case callStackField:
+			// 已在上方写入 CallStack
 		default:
 			logEntry.Extra[key] = value
 		}
@@ -136,3 +101,64 @@ func (f *OrderedJSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 
 	return append(jsonData, '\n'), nil
 }
+
+const (
+	callStackField = "call_stack"
+	maxStackDepth  = 32
+)
+
+// logPackage 本包路径，采集调用栈时跳过日志库内部的帧
+var logPackage = reflect.TypeOf(CallerInfo{}).PkgPath()
+
+func needStack(level logrus.Level) bool {
+	return level == logrus.ErrorLevel || level == logrus.WarnLevel
+}
+
+// captureStack 一次性取出调用栈，跳过 logrus 与本包的封装帧，最多保留 maxStackDepth 帧
+func (f *OrderedJSONFormatter) captureStack() []CallerInfo {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	callStack := make([]CallerInfo, 0, 16)
+	internal := true
+	for {
+		frame, more := frames.Next()
+		if internal && isLogFrame(frame.Function) {
+			if !more {
+				break
+			}
+			continue
+		}
+		internal = false
+
+		// 过滤掉不属于本地项目的调用信息
+		if !strings.Contains(frame.File, "vendor") && (f.StackFilter == "" || strings.Contains(frame.File, f.StackFilter)) {
+			callStack = append(callStack, CallerInfo{
+				File:     filepath.Dir(frame.File),
+				Function: frame.Function,
+				Line:     frame.Line,
+			})
+			if len(callStack) == maxStackDepth {
+				break
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return callStack
+}
+
+func isLogFrame(function string) bool {
+	if strings.HasPrefix(function, "github.com/sirupsen/logrus.") {
+		return true
+	}
+	if !strings.HasPrefix(function, logPackage+".") {
+		return false
+	}
+	// 只跳过封装层，本包中的中间件（如 RequestLogger）仍作为调用方保留
+	rest := function[len(logPackage)+1:]
+	return strings.HasPrefix(rest, "(*logWrapper)") || strings.HasPrefix(rest, "(*sinkHook)") ||
+		strings.HasPrefix(rest, "(*OrderedJSONFormatter)") || strings.HasPrefix(rest, "captureStack")
+}
@@ -0,0 +1,246 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// levelState 运行时日志级别：全局级别、模块级别，以及按 trace-id / 路径前缀临时开启的 debug。
+// logrus.Logger 的级别取其中最详细的一个，logWrapper 打印前再按各自的模块、trace-id、路径过滤；
+// 直接使用 logger 的日志（RequestLogger、GormLogger、GetLogger）在输出前由 entryEnabled 按同样的规则过滤。
+type levelState struct {
+	mu      sync.RWMutex
+	global  logrus.Level
+	modules map[string]logrus.Level
+	traces  map[string]time.Time // trace-id -> 过期时间
+	paths   map[string]time.Time // 路径前缀 -> 过期时间
+}
+
+var levels = &levelState{
+	global:  logrus.DebugLevel,
+	modules: map[string]logrus.Level{},
+	traces:  map[string]time.Time{},
+	paths:   map[string]time.Time{},
+}
+
+// LevelSnapshot 当前日志级别配置
+type LevelSnapshot struct {
+	Level       string               `json:"level"`
+	Modules     map[string]string    `json:"modules,omitempty"`
+	DebugTraces map[string]time.Time `json:"debug_traces,omitempty"`
+	DebugPaths  map[string]time.Time `json:"debug_paths,omitempty"`
+	LoggerLevel string               `json:"logger_level"`
+}
+
+// SetLevel 运行时修改全局日志级别
+func SetLevel(level logrus.Level) {
+	levels.mu.Lock()
+	levels.global = level
+	levels.mu.Unlock()
+	syncLoggerLevel()
+}
+
+// GetLevel 返回全局日志级别
+func GetLevel() logrus.Level {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	return levels.global
+}
+
+// SetModuleLevel 设置模块级别，log.Ctx(ctx).Module(name) 打印的日志按该级别过滤
+func SetModuleLevel(module string, level logrus.Level) {
+	levels.mu.Lock()
+	levels.modules[module] = level
+	levels.mu.Unlock()
+	syncLoggerLevel()
+}
+
+// SetModuleLevels 整体替换模块级别，用于配置热更新
+func SetModuleLevels(modules map[string]logrus.Level) {
+	levels.mu.Lock()
+	levels.modules = make(map[string]logrus.Level, len(modules))
+	for k, v := range modules {
+		levels.modules[k] = v
+	}
+	levels.mu.Unlock()
+	syncLoggerLevel()
+}
+
+// RemoveModuleLevel 删除模块级别，恢复使用全局级别
+func RemoveModuleLevel(module string) {
+	levels.mu.Lock()
+	delete(levels.modules, module)
+	levels.mu.Unlock()
+	syncLoggerLevel()
+}
+
+// EnableDebugForTrace 在 ttl 内对指定 trace-id 的请求打印 debug 日志，用于线上排查单个请求
+func EnableDebugForTrace(traceId string, ttl time.Duration) {
+	levels.enableDebug(false, traceId, ttl)
+}
+
+// EnableDebugForPath 在 ttl 内对路径以 prefix 开头的请求打印 debug 日志。
+// 路径从 *gin.Context 读取，传入普通 context.Context 时仅 trace-id 规则生效
+func EnableDebugForPath(prefix string, ttl time.Duration) {
+	levels.enableDebug(true, prefix, ttl)
+}
+
+// DisableDebugOverrides 清除全部临时 debug 规则
+func DisableDebugOverrides() {
+	levels.mu.Lock()
+	levels.traces = map[string]time.Time{}
+	levels.paths = map[string]time.Time{}
+	levels.mu.Unlock()
+	syncLoggerLevel()
+}
+
+// Levels 返回当前级别配置，已过期的临时规则不返回
+func Levels() LevelSnapshot {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	now := time.Now()
+	snapshot := LevelSnapshot{
+		Level:       levels.global.String(),
+		Modules:     make(map[string]string, len(levels.modules)),
+		DebugTraces: map[string]time.Time{},
+		DebugPaths:  map[string]time.Time{},
+	}
+	for k, v := range levels.modules {
+		snapshot.Modules[k] = v.String()
+	}
+	for k, v := range levels.traces {
+		if v.After(now) {
+			snapshot.DebugTraces[k] = v
+		}
+	}
+	for k, v := range levels.paths {
+		if v.After(now) {
+			snapshot.DebugPaths[k] = v
+		}
+	}
+	if logger != nil {
+		snapshot.LoggerLevel = logger.GetLevel().String()
+	}
+	return snapshot
+}
+
+func (s *levelState) enableDebug(byPath bool, key string, ttl time.Duration) {
+	if key == "" || ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	if byPath {
+		s.paths[key] = time.Now().Add(ttl)
+	} else {
+		s.traces[key] = time.Now().Add(ttl)
+	}
+	s.mu.Unlock()
+	syncLoggerLevel()
+
+	// 过期后收回 logrus.Logger 的级别
+	time.AfterFunc(ttl, syncLoggerLevel)
+}
+
+// level 返回指定模块、trace-id、路径的生效级别
+func (s *levelState) level(module, traceId, path string) logrus.Level {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	level := s.global
+	if module != "" {
+		if l, ok := s.modules[module]; ok {
+			level = l
+		}
+	}
+	if level >= logrus.DebugLevel || (len(s.traces) == 0 && len(s.paths) == 0) {
+		return level
+	}
+
+	now := time.Now()
+	if expire, ok := s.traces[traceId]; ok && expire.After(now) {
+		return logrus.DebugLevel
+	}
+	if path != "" {
+		for prefix, expire := range s.paths {
+			if expire.After(now) && strings.HasPrefix(path, prefix) {
+				return logrus.DebugLevel
+			}
+		}
+	}
+	return level
+}
+
+// syncLoggerLevel 清理过期规则，并把 logrus.Logger 的级别设为所有规则中最详细的一个
+func syncLoggerLevel() {
+	levels.mu.Lock()
+	now := time.Now()
+	for k, v := range levels.traces {
+		if !v.After(now) {
+			delete(levels.traces, k)
+		}
+	}
+	for k, v := range levels.paths {
+		if !v.After(now) {
+			delete(levels.paths, k)
+		}
+	}
+
+	level := levels.global
+	for _, l := range levels.modules {
+		if l > level {
+			level = l
+		}
+	}
+	if (len(levels.traces) > 0 || len(levels.paths) > 0) && level < logrus.DebugLevel {
+		level = logrus.DebugLevel
+	}
+	levels.mu.Unlock()
+
+	if logger != nil {
+		logger.SetLevel(level)
+	}
+}
+
+// entryEnabled 按日志的 module、trace-id 字段与路径判断是否输出。
+// logrus.Logger 的级别被模块级别或临时 debug 调高后，不满足规则的日志在这里丢弃，
+// 避免某个 trace 开启 debug 时所有请求的 debug/info 日志都被输出
+func entryEnabled(entry *logrus.Entry) bool {
+	module, _ := entry.Data["module"].(string)
+	traceId, _ := entry.Data[TraceId].(string)
+	path, _ := entry.Data["path"].(string)
+	if path == "" {
+		if c, ok := entry.Context.(*gin.Context); ok && c.Request != nil {
+			path = c.Request.URL.Path
+		}
+	}
+	return entry.Level <= levels.level(module, traceId, path)
+}
+
+// levelGate 包装终端输出的 Formatter，返回空内容即不输出
+type levelGate struct {
+	logrus.Formatter
+}
+
+func (f levelGate) Format(entry *logrus.Entry) ([]byte, error) {
+	if !entryEnabled(entry) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// gatedHook 包装 AddHook 添加的 hook，按级别规则过滤后再执行
+type gatedHook struct {
+	logrus.Hook
+}
+
+func (h gatedHook) Fire(entry *logrus.Entry) error {
+	if !entryEnabled(entry) {
+		return nil
+	}
+	return h.Hook.Fire(entry)
+}
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"github.com/sirupsen/logrus"
+	gLog "gorm.io/gorm/logger"
+)
+
+func TestRuntimeLevels(t *testing.T) {
+	buf := captureLogger(t)
+	t.Cleanup(func() {
+		SetModuleLevels(nil)
+		DisableDebugOverrides()
+	})
+
+	SetLevel(logrus.InfoLevel)
+	SetModuleLevel("payment", logrus.DebugLevel)
+	if logger.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("logger level %s should follow most verbose module", logger.GetLevel())
+	}
+
+	ctx := ctxkeys.WithTraceId(context.Background(), "trace-debug")
+	Ctx(ctx).Debug("global debug")
+	Ctx(ctx).Module("payment").Debug("payment debug")
+	if strings.Contains(buf.String(), "global debug") || !strings.Contains(buf.String(), "payment debug") {
+		t.Fatalf("module level not applied: %s", buf.String())
+	}
+
+	// 临时 debug 只对指定 trace-id 生效，过期后恢复
+	EnableDebugForTrace("trace-debug", 50*time.Millisecond)
+	Ctx(ctx).Debug("trace debug")
+	Ctx(context.Background()).Debug("other debug")
+	if !strings.Contains(buf.String(), "trace debug") || strings.Contains(buf.String(), "other debug") {
+		t.Fatalf("trace override not applied: %s", buf.String())
+	}
+	time.Sleep(80 * time.Millisecond)
+	Ctx(ctx).Debug("expired debug")
+	if strings.Contains(buf.String(), "expired debug") {
+		t.Fatal("trace override not expired")
+	}
+}
+
+func TestDebugOverrideDirectLogger(t *testing.T) {
+	buf := captureLogger(t)
+	t.Cleanup(DisableDebugOverrides)
+
+	SetLevel(logrus.WarnLevel)
+	EnableDebugForTrace("trace-debug", time.Minute)
+	if logger.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("logger level %s should be raised for trace override", logger.GetLevel())
+	}
+
+	// RequestLogger、GormLogger 与 GetLogger 直接使用 logger，同样只对指定 trace 输出 debug
+	GetLogger().Info("direct info")
+	logger.WithField(TraceId, "other").Debug("other trace debug")
+	logger.WithField(TraceId, "trace-debug").Debug("override debug")
+	gormLogger := NewGormLogger(SetGormLogLevel(gLog.Info))
+	gormLogger.Info(ctxkeys.WithTraceId(context.Background(), "other"), "gorm other")
+	gormLogger.Info(ctxkeys.WithTraceId(context.Background(), "trace-debug"), "gorm trace")
+	out := buf.String()
+	if strings.Contains(out, "direct info") || strings.Contains(out, "other trace debug") || strings.Contains(out, "gorm other") {
+		t.Fatalf("direct logger not filtered: %s", out)
+	}
+	if !strings.Contains(out, "override debug") || !strings.Contains(out, "gorm trace") {
+		t.Fatalf("trace override not applied to direct logger: %s", out)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	captureLogger(t)
+	t.Cleanup(func() {
+		SetModuleLevels(nil)
+		DisableDebugOverrides()
+	})
+
+	r := gin.New()
+	RegisterAdmin(r, "", "secret")
+	r.GET("/orders/:id", func(c *gin.Context) {
+		Ctx(c).Debug("path debug")
+	})
+
+	put := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPut, DefaultAdminPath, strings.NewReader(body))
+		req.Header.Set(AdminTokenHeader, token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := put("wrong", `{"level":"error"}`); code != http.StatusUnauthorized {
+		t.Fatalf("wrong token status %d", code)
+	}
+	if code := put("secret", `{"level":"verbose"}`); code != http.StatusBadRequest {
+		t.Fatalf("invalid level status %d", code)
+	}
+	if code := put("secret", `{"level":"warn","modules":{"payment":"debug"},"debug_path":"/orders/","ttl":60}`); code != http.StatusOK {
+		t.Fatalf("update status %d", code)
+	}
+	snapshot := Levels()
+	if snapshot.Level != "warning" || snapshot.Modules["payment"] != "debug" || len(snapshot.DebugPaths) != 1 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	if levels.level("", "", "/orders/1") != logrus.DebugLevel || levels.level("", "", "/users/1") != logrus.WarnLevel {
+		t.Fatal("path override not applied")
+	}
+}
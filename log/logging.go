@@ -2,58 +2,101 @@ package log
 
 import (
 	"context"
-	"github.com/hyzx-go/common-b2c/global"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"github.com/hyzx-go/common-b2c/errclass"
 	"io/ioutil"
 	"os"
 	"sync"
-	"time"
 
 	"github.com/sirupsen/logrus"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
-	TraceId = "trace-id"
+	// TraceId 日志字段名，读写 context 请使用 ctxkeys
+	TraceId = ctxkeys.GinTraceIdKey
 )
 
 var (
 	logger *logrus.Logger
 	once   sync.Once
+
+	// initMu 保护 InitLogger 的重复调用，activeSinks 为当前使用中的日志输出
+	initMu      sync.Mutex
+	activeSinks []*sinkHook
+	// extraHooks 通过 AddHook 添加的 hook，InitLogger 重新设置输出后保留
+	extraHooks []logrus.Hook
 )
 
+func init() {
+	// Fatal 退出前写完异步队列
+	logrus.RegisterExitHandler(CloseSinks)
+}
+
 // GetLogger returns the singleton logger instance.
 func GetLogger() *logrus.Entry {
-	if logger == nil {
-		InitLogger(Config{DefaultConf: DefaultConfig()}) // Use default config if not initialized
-	}
+	ensureLogger()
 	return logrus.NewEntry(logger)
 }
 
+// ensureLogger 未初始化时使用默认配置初始化。
+// 配置加载前打印的日志（如 system 配置）会触发默认初始化，之后 InitLogger 仍可按配置重新设置
+func ensureLogger() {
+	once.Do(func() {
+		if logger == nil {
+			InitLogger(Config{DefaultConf: DefaultConfig()})
+		}
+	})
+}
+
 // logWrapper 结构体用于封装日志相关的方法
 type logWrapper struct {
 	log     *logrus.Entry
 	ctx     context.Context
 	traceId string
+	module  string
+	path    string
 }
 
+// Fields 日志字段
+type Fields = logrus.Fields
+
 // Ctx 创建一个新的 logWrapper 实例
 func Ctx(ctx context.Context) *logWrapper {
-	if logger == nil {
-		InitLogger(Config{DefaultConf: DefaultConfig()})
-	}
+	ensureLogger()
 
 	if ctx == nil {
 		return &logWrapper{log: logrus.NewEntry(logger)}
 	}
 
-	traceId := ctx.Value(TraceId)
-	if traceId == nil {
-		traceId = "unknown"
+	// 中间件通过 ctxkeys.SetLogFields 写入的字段（user_id、tenant_id 等）随每条日志输出；
+	// 输出前按 context 中的路径过滤级别，见 entryEnabled
+	entry := logrus.NewEntry(logger).WithContext(ctx).WithFields(ctxkeys.LogFields(ctx))
+	lw := &logWrapper{log: entry, ctx: ctx, traceId: traceIdFromContext(ctx)}
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		lw.path = c.Request.URL.Path
 	}
-	return &logWrapper{log: logrus.NewEntry(logger), ctx: ctx, traceId: traceId.(string)}
+	return lw
+}
+
+// WithFields 返回追加了请求级日志字段的 context，之后 Ctx(ctx) 打印的日志都会带上；
+// gin 中间件请使用 ctxkeys.SetLogFields，字段会同时写入 gin.Context 与 c.Request.Context()
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	return ctxkeys.WithLogFields(ctx, fields)
+}
+
+// traceIdFromContext 读取 trace-id，没有时返回 unknown
+func traceIdFromContext(ctx context.Context) string {
+	if traceId := ctxkeys.TraceId(ctx); traceId != "" {
+		return traceId
+	}
+	return "unknown"
 }
 
 // InitLogger initializes the logger with the provided configuration.
+// 可重复调用：已初始化时原地更新级别、输出与滚动文件，已持有的 logger 引用继续有效。
+// LogLevel 为零值（PanicLevel）时视为未设置，使用 DebugLevel。
 func InitLogger(config Config) {
 	defaultConf := DefaultConfig()
 	if config.DefaultConf == nil {
@@ -67,8 +110,8 @@ func InitLogger(config Config) {
 			config.DefaultConf.File = defaultConf.File
 		}
 
-		if config.LogLevel < logrus.DebugLevel {
-			config.LogLevel = logrus.DebugLevel
+		if config.LogLevel == logrus.PanicLevel || config.LogLevel > logrus.TraceLevel {
+			config.LogLevel = defaultConf.LogLevel
 		}
 
 		if config.MaxSize == 0 {
@@ -80,107 +123,310 @@ func InitLogger(config Config) {
 		if config.MaxAge == 0 {
 			config.MaxAge = defaultConf.MaxAge
 		}
+		if config.BufferSize == 0 {
+			config.BufferSize = defaultConf.BufferSize
+		}
 	}
 
-	once.Do(func() {
-		// 检查并创建日志目录
-		if err := os.MkdirAll(config.Dir, 0755); err != nil {
-			logger.Fatal("Failed to create log directory:", err)
-		}
+	initMu.Lock()
+	defer initMu.Unlock()
 
+	if logger == nil {
 		logger = logrus.New()
+	}
 
-		// 设置日志级别
-		logger.SetLevel(config.LogLevel)
+	// 设置全局日志级别，logrus.Logger 的级别还需考虑模块级别与临时 debug 规则
+	levels.mu.Lock()
+	levels.global = config.LogLevel
+	levels.mu.Unlock()
+	syncLoggerLevel()
 
-		// 启用 ReportCaller 以显示文件名和行号
-		logger.SetReportCaller(config.ReportCaller)
+	// 启用 ReportCaller 以显示文件名和行号
+	logger.SetReportCaller(config.ReportCaller)
 
-		// 配置滚动日志文件
-		jsonLogFile := &lumberjack.Logger{
-			Filename:   config.Dir + "/" + config.File,
+	// EnableFileOutput 对应一个 JSON 文件 sink，默认异步写入
+	sinks := config.Sinks
+	if config.EnableFileOutput {
+		bufferSize := config.BufferSize
+		if bufferSize < 0 {
+			bufferSize = 0
+		}
+		sinks = append([]SinkConfig{{
+			Type:       SinkFile,
+			Format:     FormatJSON,
+			Dir:        config.Dir,
+			File:       config.File,
 			MaxSize:    config.MaxSize,
 			MaxBackups: config.MaxBackups,
 			MaxAge:     config.MaxAge,
 			Compress:   config.Compress,
-		}
+			BufferSize: bufferSize,
+			Overflow:   config.Overflow,
+			SampleRate: config.SampleRate,
+		}}, sinks...)
+	}
 
-		// 配置文本格式和 JSON 格式的 Hook
-		hook := &jSONAndTextFormatterHook{
-			// 添加全局字段
-			fields:     global.LogPreInfo,
-			JSONWriter: jsonLogFile,
-			JSONFormat: &OrderedJSONFormatter{
-				TimestampFormat: time.RFC3339,
-			},
+	// 脱敏需先于所有输出执行
+	hooks := make(logrus.LevelHooks)
+	hooks.Add(redactHook{})
+	previous := activeSinks
+	activeSinks = nil
+	for _, sinkConf := range sinks {
+		hook, err := newSinkHook(sinkConf, config.StackFilter)
+		if err != nil {
+			// 单个 sink 创建失败不影响其他输出
+			fmt.Fprintf(os.Stderr, "log: init %s sink failed: %v\n", sinkConf.Type, err)
+			continue
 		}
+		hooks.Add(hook)
+		activeSinks = append(activeSinks, hook)
+	}
+	for _, hook := range extraHooks {
+		hooks.Add(gatedHook{hook})
+	}
+	logger.ReplaceHooks(hooks)
+	// 终端输出同样按模块级别与临时 debug 规则过滤
+	if _, ok := logger.Formatter.(levelGate); !ok {
+		logger.SetFormatter(levelGate{logger.Formatter})
+	}
+
+	if config.EnableTerminalOutput {
+		// 将日志的主输出设置为终端
+		logger.SetOutput(os.Stdout)
+	} else {
+		logger.SetOutput(ioutil.Discard)
+	}
+
+	for _, hook := range previous {
+		_ = hook.Close()
+	}
+}
+
+// Flush 等待所有 sink 写完队列中已有的日志
+func Flush() {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	for _, hook := range activeSinks {
+		_ = hook.Flush()
+	}
+}
 
-		if config.EnableFileOutput {
-			// 将 Hook 添加到 logrus
-			logger.AddHook(hook)
+// CloseSinks 写完异步队列中的日志并关闭所有 sink，进程退出前调用
+func CloseSinks() {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	for _, hook := range activeSinks {
+		_ = hook.Close()
+	}
+	activeSinks = nil
+	if logger != nil {
+		hooks := make(logrus.LevelHooks)
+		hooks.Add(redactHook{})
+		for _, hook := range extraHooks {
+			hooks.Add(gatedHook{hook})
 		}
+		logger.ReplaceHooks(hooks)
+	}
+}
+
+// AddHook 添加 hook（如告警），在脱敏与日志输出之后执行，重新 InitLogger 后仍然生效
+func AddHook(hook logrus.Hook) {
+	ensureLogger()
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	extraHooks = append(extraHooks, hook)
+	logger.AddHook(gatedHook{hook})
+}
+
+// RemoveHook 移除 AddHook 添加的 hook
+func RemoveHook(hook logrus.Hook) {
+	initMu.Lock()
+	defer initMu.Unlock()
 
-		if config.EnableTerminalOutput {
-			// 将日志的主输出设置为终端
-			logger.SetOutput(os.Stdout)
-		} else {
-			logger.SetOutput(ioutil.Discard)
+	kept := extraHooks[:0]
+	for _, h := range extraHooks {
+		if h != hook {
+			kept = append(kept, h)
 		}
-	})
+	}
+	extraHooks = kept
+	if logger == nil {
+		return
+	}
+	hooks := make(logrus.LevelHooks)
+	for level, list := range logger.Hooks {
+		for _, h := range list {
+			if h != (gatedHook{hook}) {
+				hooks[level] = append(hooks[level], h)
+			}
+		}
+	}
+	logger.ReplaceHooks(hooks)
 }
 
-// Info 封装 Info 级别的日志打印
-func (lw *logWrapper) Info(keyword string, messages ...interface{}) {
+// With 追加一个字段，返回新的 logWrapper，原实例不受影响
+func (lw *logWrapper) With(key string, value interface{}) *logWrapper {
+	return lw.WithFields(Fields{key: value})
+}
 
-	var message interface{}
-	if len(messages) > 0 {
-		message = messages[0]
+// WithFields 追加多个字段，返回新的 logWrapper，原实例不受影响
+func (lw *logWrapper) WithFields(fields Fields) *logWrapper {
+	clone := *lw
+	clone.log = lw.log.WithFields(fields)
+	return &clone
+}
+
+// Module 标记日志所属模块，按 SetModuleLevel 设置的模块级别过滤
+func (lw *logWrapper) Module(name string) *logWrapper {
+	clone := lw.With("module", name)
+	clone.module = name
+	return clone
+}
+
+// enabled 按模块级别与临时 debug 规则判断是否需要打印
+func (lw *logWrapper) enabled(level logrus.Level) bool {
+	return levels.level(lw.module, lw.traceId, lw.path) >= level
+}
+
+// Debug 封装 Debug 级别的日志打印
+func (lw *logWrapper) Debug(keyword string, messages ...interface{}) {
+	if !lw.enabled(logrus.DebugLevel) {
+		return
 	}
+	lw.entry(messages).Debug(keyword)
+}
 
-	lw.log.WithFields(logrus.Fields{
-		"message":  message,
-		"trace-id": lw.traceId, // 假设你在 gin.Context 中存储了 trace_id
-	}).Info(keyword)
+// Info 封装 Info 级别的日志打印
+func (lw *logWrapper) Info(keyword string, messages ...interface{}) {
+	if !lw.enabled(logrus.InfoLevel) {
+		return
+	}
+	lw.entry(messages).Info(keyword)
 }
 
 // Warn 封装 Warn 级别的日志打印
 func (lw *logWrapper) Warn(keyword string, messages ...interface{}) {
-	var message interface{}
-	if len(messages) > 0 {
-		message = messages[0]
+	if !lw.enabled(logrus.WarnLevel) {
+		return
 	}
+	lw.entry(messages).Warn(keyword)
+}
 
-	// 检查 err 是否为 error 类型
-	if e, ok := message.(error); ok {
-		message = e.Error() // 将 error 信息转换为字符串并赋值给 err
+// Error 封装 Error 级别的日志打印，messages 中的错误按 errclass 分类降级为 Warn/Info，并记录 error_class
+func (lw *logWrapper) Error(keyword string, messages ...interface{}) {
+	level, class := errorLevel(messages)
+	if class != "" {
+		lw = lw.With(ErrorClassField, class)
+	}
+	if !lw.enabled(level) {
+		return
 	}
+	lw.entry(messages).Log(level, keyword)
+}
 
-	lw.log.WithFields(logrus.Fields{
-		"message":  message,
-		"trace-id": lw.traceId,
-	}).Warn(keyword)
+// Fatal 封装 Fatal 级别的日志打印，打印后进程退出
+func (lw *logWrapper) Fatal(keyword string, messages ...interface{}) {
+	lw.entry(messages).Fatal(keyword)
 }
 
-// Error 封装 Error 级别的日志打印
-func (lw *logWrapper) Error(keyword string, messages ...interface{}) {
+// Debugf 格式化 keyword 后按 Debug 级别打印
+func (lw *logWrapper) Debugf(format string, args ...interface{}) {
+	if !lw.enabled(logrus.DebugLevel) {
+		return
+	}
+	lw.entry(nil).Debugf(format, args...)
+}
+
+// Infof 格式化 keyword 后按 Info 级别打印
+func (lw *logWrapper) Infof(format string, args ...interface{}) {
+	if !lw.enabled(logrus.InfoLevel) {
+		return
+	}
+	lw.entry(nil).Infof(format, args...)
+}
+
+// Warnf 格式化 keyword 后按 Warn 级别打印
+func (lw *logWrapper) Warnf(format string, args ...interface{}) {
+	if !lw.enabled(logrus.WarnLevel) {
+		return
+	}
+	lw.entry(nil).Warnf(format, args...)
+}
+
+// Errorf 格式化 keyword 后按 Error 级别打印，args 中的错误按 errclass 分类降级为 Warn/Info
+func (lw *logWrapper) Errorf(format string, args ...interface{}) {
+	level, class := errorLevel(args)
+	if class != "" {
+		lw = lw.With(ErrorClassField, class)
+	}
+	if !lw.enabled(level) {
+		return
+	}
+	lw.entry(nil).Logf(level, format, args...)
+}
+
+// Fatalf 格式化 keyword 后按 Fatal 级别打印，打印后进程退出
+func (lw *logWrapper) Fatalf(format string, args ...interface{}) {
+	lw.entry(nil).Fatalf(format, args...)
+}
+
+// entry 组装 message 与 trace-id 字段。
+// 只有一条 message 时保持原样输出，多条时按数组输出；error 统一转换为字符串
+func (lw *logWrapper) entry(messages []interface{}) *logrus.Entry {
 	var message interface{}
-	if len(messages) > 0 {
-		message = messages[0]
+	switch len(messages) {
+	case 0:
+	case 1:
+		message = errorString(messages[0])
+	default:
+		list := make([]interface{}, len(messages))
+		for i, m := range messages {
+			list[i] = errorString(m)
+		}
+		message = list
+	}
+
+	fields := logrus.Fields{"trace-id": lw.traceId}
+	if message != nil {
+		fields["message"] = message
 	}
+	return lw.log.WithFields(fields)
+}
 
-	// 检查 err 是否为 error 类型
+func errorString(message interface{}) interface{} {
 	if e, ok := message.(error); ok {
-		// 需要报警的错误列表
+		return e.Error()
+	}
+	return message
+}
+
+// errorLevel 按第一个已分类的错误确定级别与分类名，未分类时为 Error
+func errorLevel(messages []interface{}) (logrus.Level, string) {
+	for _, m := range messages {
+		e, ok := m.(error)
+		if !ok {
+			continue
+		}
+		if class, ok := errclass.Classify(e); ok {
+			return severityLevel(class.Severity), class.Name
+		}
 		if IsWarnError(e) {
-			lw.Warn(keyword, e)
-			return
+			return logrus.WarnLevel, ""
 		}
-
-		message = e.Error() // 将 error 信息转换为字符串并赋值给 err
 	}
+	return logrus.ErrorLevel, ""
+}
 
-	lw.log.WithFields(logrus.Fields{
-		"message":  message,
-		"trace-id": lw.traceId,
-	}).Error(keyword)
+func severityLevel(severity errclass.Severity) logrus.Level {
+	switch severity {
+	case errclass.SeverityWarn:
+		return logrus.WarnLevel
+	case errclass.SeverityInfo:
+		return logrus.InfoLevel
+	default:
+		return logrus.ErrorLevel
+	}
 }
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"github.com/hyzx-go/common-b2c/errclass"
+	"github.com/hyzx-go/common-b2c/global"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func captureLogger(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	// 不替换全局 logger，避免与级别同步的 goroutine 竞争
+	InitLogger(Config{DefaultConf: &DefaultConf{LogLevel: logrus.DebugLevel}})
+	logger.SetOutput(buf)
+	logger.SetFormatter(levelGate{&logrus.JSONFormatter{}})
+	t.Cleanup(func() {
+		InitLogger(Config{DefaultConf: &DefaultConf{LogLevel: logrus.InfoLevel}})
+		logger.SetFormatter(levelGate{&logrus.TextFormatter{}})
+	})
+	return buf
+}
+
+func lastLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &data); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	return data
+}
+
+func TestLogWrapperFields(t *testing.T) {
+	buf := captureLogger(t)
+
+	ctx := ctxkeys.WithTraceId(context.Background(), "trace-1")
+	ctx = WithFields(ctx, Fields{"user_id": "u1"})
+	ctx = WithFields(ctx, Fields{"tenant_id": "t1"})
+
+	lw := Ctx(ctx)
+	lw.With("order_id", 42).Info("order created", "ok")
+	data := lastLine(t, buf)
+	if data["user_id"] != "u1" || data["tenant_id"] != "t1" || data["order_id"] != float64(42) || data["trace-id"] != "trace-1" {
+		t.Fatalf("unexpected fields %v", data)
+	}
+
+	// With 不修改原实例
+	lw.Info("no order")
+	if _, ok := lastLine(t, buf)["order_id"]; ok {
+		t.Fatal("With leaked into parent wrapper")
+	}
+
+	lw.Debug("multi", "a", errors.New("b"))
+	if msg, _ := lastLine(t, buf)["message"].([]interface{}); len(msg) != 2 || msg[1] != "b" {
+		t.Fatalf("multi message %v", lastLine(t, buf)["message"])
+	}
+
+	lw.Infof("paid %d", 100)
+	if data := lastLine(t, buf); data["msg"] != "paid 100" || data["level"] != "info" {
+		t.Fatalf("infof %v", data)
+	}
+
+	lw.Errorf("query failed: %v", gorm.ErrDuplicatedKey)
+	if data := lastLine(t, buf); data["level"] != "warning" {
+		t.Fatalf("warn error not downgraded: %v", data)
+	}
+	lw.Error("query failed", "detail", gorm.ErrDuplicatedKey)
+	if data := lastLine(t, buf); data["level"] != "warning" || data[ErrorClassField] != "duplicated_key" {
+		t.Fatalf("warn error not downgraded: %v", data)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	buf := captureLogger(t)
+	lw := Ctx(context.Background())
+
+	errSoldOut := errors.New("sold out")
+	errclass.Register(errclass.Class{Name: "sold_out", Severity: errclass.SeverityInfo}, errclass.Sentinel(errSoldOut))
+	lw.Error("buy failed", fmt.Errorf("sku 1: %w", errSoldOut))
+	if data := lastLine(t, buf); data["level"] != "info" || data[ErrorClassField] != "sold_out" {
+		t.Fatalf("registered class not applied: %v", data)
+	}
+
+	// WarnErrorSlice 仍然生效
+	errLegacy := errors.New("legacy upstream busy")
+	WarnErrorSlice = append(WarnErrorSlice, errLegacy)
+	t.Cleanup(func() { WarnErrorSlice = WarnErrorSlice[:len(WarnErrorSlice)-1] })
+	if !IsWarnError(errors.New("call: legacy upstream busy")) {
+		t.Fatal("WarnErrorSlice no longer matched")
+	}
+	lw.Error("call failed", errLegacy)
+	if data := lastLine(t, buf); data["level"] != "warning" {
+		t.Fatalf("legacy warn error not downgraded: %v", data)
+	}
+
+	lw.Error("call failed", errors.New("boom"))
+	if data := lastLine(t, buf); data["level"] != "error" {
+		t.Fatalf("unclassified error downgraded: %v", data)
+	}
+	if IsWarnError(nil) {
+		t.Fatal("nil is not a warn error")
+	}
+}
+
+func TestInitLoggerReconfigure(t *testing.T) {
+	dir := t.TempDir()
+	oldPre := global.LogPreInfo
+	t.Cleanup(func() {
+		global.LogPreInfo = oldPre
+		InitLogger(Config{DefaultConf: &DefaultConf{LogLevel: logrus.InfoLevel}})
+	})
+
+	// 首次初始化后再按配置初始化，第二次的设置必须生效
+	InitLogger(Config{DefaultConf: &DefaultConf{Dir: dir, File: "first.log", EnableFileOutput: true}})
+	InitLogger(Config{DefaultConf: &DefaultConf{LogLevel: logrus.WarnLevel, Dir: dir, File: "app.log", EnableFileOutput: true}})
+	if logger.GetLevel() != logrus.WarnLevel {
+		t.Fatalf("level %s want warning", logger.GetLevel())
+	}
+
+	// 全局字段在初始化之后设置，同样写入日志
+	global.LogPreInfo = logrus.Fields{"app_name": "order-svc"}
+	Ctx(context.Background()).Info("dropped")
+	Ctx(context.Background()).Warn("kept")
+	Flush()
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "dropped") || !strings.Contains(string(data), "kept") {
+		t.Fatalf("unexpected file content %s", data)
+	}
+	if !strings.Contains(string(data), `"app_name":"order-svc"`) {
+		t.Fatalf("LogPreInfo missing: %s", data)
+	}
+}
+
+type countHook struct{ fired int }
+
+func (h *countHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *countHook) Fire(*logrus.Entry) error {
+	h.fired++
+	return nil
+}
+
+func TestAddHookSurvivesInit(t *testing.T) {
+	captureLogger(t)
+	hook := &countHook{}
+	AddHook(hook)
+	t.Cleanup(func() { RemoveHook(hook) })
+
+	InitLogger(Config{DefaultConf: &DefaultConf{LogLevel: logrus.DebugLevel}})
+	logger.SetOutput(io.Discard)
+	Ctx(context.Background()).Info("after init")
+	if hook.fired != 1 {
+		t.Fatalf("hook fired %d times, want 1", hook.fired)
+	}
+
+	RemoveHook(hook)
+	Ctx(context.Background()).Info("after remove")
+	if hook.fired != 1 {
+		t.Fatal("removed hook still fired")
+	}
+}
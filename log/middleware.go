@@ -5,129 +5,460 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
 	"github.com/hyzx-go/common-b2c/utils"
 	"github.com/sirupsen/logrus"
 	"io"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
-// RequestLogger 是一个记录请求日志的中间件
+// RequestLogOptions 请求日志的记录范围
+type RequestLogOptions struct {
+	ExcludePaths      []string      // 不记录的路径，以 * 结尾时按前缀匹配
+	MaxBodySize       int           // 记录的响应体最大字节数，超出部分截断，0 使用默认值，小于 0 时不记录响应体
+	BodyContentTypes  []string      // 记录响应体的 Content-Type，支持 text/* 形式
+	SuccessSampleRate float64       // 成功请求的采样率 0~1，1 为全部记录；错误与慢请求始终记录
+	SlowThreshold     time.Duration // 慢请求阈值
+	MaxParamsSize     int           // 请求体参数最多读取的字节数，0 使用默认值
+	Headers           []string      // 记录的请求头，按脱敏规则处理
+}
+
+// DefaultRequestLogOptions 默认记录全部请求，响应体只记录 4KB 以内的 JSON/文本，排除 /metrics
+func DefaultRequestLogOptions() RequestLogOptions {
+	return RequestLogOptions{
+		ExcludePaths:      []string{"/metrics"},
+		MaxBodySize:       4096,
+		BodyContentTypes:  []string{"application/json", "application/problem+json", "text/plain", "text/xml", "application/xml"},
+		SuccessSampleRate: 1,
+		SlowThreshold:     SlowApiThreshold,
+		MaxParamsSize:     16 << 10,
+		Headers:           []string{"User-Agent", "Content-Type", "Referer", "X-Forwarded-For"},
+	}
+}
+
+var requestLogOptions atomic.Value
+
+// SetRequestLogOptions 设置 RequestLogger 使用的默认配置，需在创建中间件之前调用
+func SetRequestLogOptions(opts RequestLogOptions) {
+	requestLogOptions.Store(opts)
+}
+
+func getRequestLogOptions() RequestLogOptions {
+	if opts, ok := requestLogOptions.Load().(RequestLogOptions); ok {
+		return opts
+	}
+	return DefaultRequestLogOptions()
+}
+
+// RequestLogger 是一个记录请求日志的中间件，参数与响应体写出前按脱敏规则处理（见 redact.go）
 func RequestLogger() gin.HandlerFunc {
+	return RequestLoggerWithOptions(getRequestLogOptions())
+}
+
+// RequestLoggerWithOptions 使用指定配置。
+// 未被采样的成功请求不记录开始日志，出错或慢请求时在结束日志中补充请求参数。
+func RequestLoggerWithOptions(opts RequestLogOptions) gin.HandlerFunc {
+	defaults := DefaultRequestLogOptions()
+	if opts.SlowThreshold <= 0 {
+		opts.SlowThreshold = defaults.SlowThreshold
+	}
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = defaults.MaxBodySize
+	}
+	if opts.BodyContentTypes == nil {
+		opts.BodyContentTypes = defaults.BodyContentTypes
+	}
+	if opts.MaxParamsSize <= 0 {
+		opts.MaxParamsSize = defaults.MaxParamsSize
+	}
+
 	return func(c *gin.Context) {
+		if pathExcluded(c.Request.URL.Path, opts.ExcludePaths) {
+			c.Next()
+			return
+		}
+
 		// 获取或生成 trace ID
 		traceID := utils.GetTraceId(c)
-		requestParams := extractRequestParams(c) // 提取请求参数
+		// 写入请求上下文，log.Ctx(c.Request.Context()) 可读取到同一个 trace-id
+		if ctxkeys.TraceId(c.Request.Context()) != traceID {
+			ctxkeys.SetTraceId(c, traceID)
+		}
+		reqParams := extractRequestParams(c, opts) // 提取请求参数
 		startTime := time.Now()
+		sampled := opts.SuccessSampleRate >= 1 || rand.Float64() < opts.SuccessSampleRate
 
 		// 包装 ResponseWriter 以捕获响应内容
-		responseWriter := &responseBodyWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		responseWriter := &responseBodyWriter{ResponseWriter: c.Writer, limit: opts.MaxBodySize, contentTypes: opts.BodyContentTypes}
 		c.Writer = responseWriter
 
 		// 记录请求开始日志
-		logRequestStart(c, traceID, requestParams)
+		if sampled {
+			logRequestStart(c, traceID, reqParams)
+		}
 
 		// 执行请求
 		c.Next()
 
 		// 请求结束后记录请求日志，包括响应内容
-		logRequestEnd(c, traceID, startTime, responseWriter.body.String())
+		duration := time.Since(startTime)
+		slow := duration > opts.SlowThreshold
+		failed := c.Writer.Status() >= http.StatusBadRequest || len(c.Errors) > 0
+		if !sampled && !slow && !failed {
+			return
+		}
+		var params *requestParams
+		if !sampled {
+			params = &reqParams
+		}
+		logRequestEnd(c, traceID, duration, slow, responseWriter.logBody(), params)
+	}
+}
+
+// pathExcluded 精确匹配，或以 * 结尾时按前缀匹配
+func pathExcluded(path string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if strings.HasSuffix(exclude, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(exclude, "*")) {
+				return true
+			}
+		} else if path == exclude {
+			return true
+		}
 	}
+	return false
 }
 
 // logRequestStart 记录请求开始时的日志
-func logRequestStart(c *gin.Context, traceID string, params map[string]interface{}) {
-	logger.WithFields(logrus.Fields{
+func logRequestStart(c *gin.Context, traceID string, params requestParams) {
+	logger.WithContext(c).WithFields(params.fields()).WithFields(logrus.Fields{
 		"trace-id":  traceID,
 		"method":    c.Request.Method,
 		"path":      c.Request.URL.Path,
 		"client_ip": c.ClientIP(),
-		"params":    params,
 	}).Info("request received start")
 }
 
-// logRequestEnd 记录请求结束时的日志
-func logRequestEnd(c *gin.Context, traceID string, startTime time.Time, responseBody string) {
-	duration := time.Since(startTime)
-
+// logRequestEnd 记录请求结束时的日志，params 不为空时一并记录（未记录开始日志的请求）
+func logRequestEnd(c *gin.Context, traceID string, duration time.Duration, slow bool, responseBody string, params *requestParams) {
 	fields := logrus.Fields{
 		"trace-id":    traceID,
 		"status_code": c.Writer.Status(),
 		"latency":     fmt.Sprintf("%.3f", duration.Seconds()),
 		"response":    responseBody, // 捕获的响应内容
 	}
+	if params != nil {
+		for k, v := range params.fields() {
+			fields[k] = v
+		}
+		fields["path"] = c.Request.URL.Path
+		fields["method"] = c.Request.Method
+		fields["client_ip"] = c.ClientIP()
+	}
 
-	if duration > SlowApiThreshold {
+	if slow {
 		fields["path"] = c.Request.URL.Path
 		fields["method"] = c.Request.Method
-		logger.WithFields(fields).Warn("slow request")
+		logger.WithContext(c).WithFields(fields).Warn("slow request")
 	}
-	logger.WithFields(fields).Info("request completed")
+	logger.WithContext(c).WithFields(fields).Info("request completed")
 }
 
 // ResponseWriter 包装器，用于捕获响应数据
 type responseBodyWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body         bytes.Buffer
+	limit        int
+	contentTypes []string
+	checked      bool // 已按 Content-Type 判断是否记录
+	capture      bool
+	truncated    int // 超出上限未记录的字节数
 }
 
-func (w responseBodyWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)                  // 将响应写入缓存
+func (w *responseBodyWriter) Write(b []byte) (int, error) {
+	w.record(b)                      // 将响应写入缓存
 	return w.ResponseWriter.Write(b) // 将响应写入实际的 ResponseWriter
 }
 
-// extractRequestParams 提取 GET 和 POST 请求的参数
-func extractRequestParams(c *gin.Context) map[string]interface{} {
-	params := make(map[string]interface{})
+func (w *responseBodyWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
 
-	switch c.Request.Method {
-	case "GET":
-		for key, values := range c.Request.URL.Query() {
-			if len(values) > 0 {
-				params[key] = values[0]
+// record 只缓存允许记录的 Content-Type，且不超过上限
+func (w *responseBodyWriter) record(b []byte) {
+	if !w.checked {
+		w.checked = true
+		w.capture = w.limit >= 0 && contentTypeAllowed(w.Header().Get("Content-Type"), w.contentTypes)
+	}
+	if !w.capture {
+		return
+	}
+	if remain := w.limit - w.body.Len(); len(b) > remain {
+		if remain > 0 {
+			w.body.Write(b[:remain])
+			b = b[remain:]
+		}
+		w.truncated += len(b)
+		return
+	}
+	w.body.Write(b)
+}
+
+// logBody 返回记录的响应体，未记录或截断时附带说明
+func (w *responseBodyWriter) logBody() string {
+	if w.checked && !w.capture {
+		return fmt.Sprintf("[omitted %s]", w.Header().Get("Content-Type"))
+	}
+	if w.truncated > 0 {
+		return fmt.Sprintf("%s...[truncated %d bytes]", w.body.String(), w.truncated)
+	}
+	return w.body.String()
+}
+
+// contentTypeAllowed 按媒体类型匹配，忽略 charset 等参数
+func contentTypeAllowed(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a == mediaType || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestParams 请求参数：query 与请求体字段合并在 Params 中，路径参数与请求头单独记录
+type requestParams struct {
+	Params     map[string]interface{}
+	PathParams map[string]string
+	Headers    map[string]interface{}
+}
+
+// fields 转换为日志字段
+func (p requestParams) fields() logrus.Fields {
+	fields := logrus.Fields{"params": p.Params}
+	if len(p.PathParams) > 0 {
+		fields["path_params"] = p.PathParams
+	}
+	if len(p.Headers) > 0 {
+		fields["headers"] = p.Headers
+	}
+	return fields
+}
+
+// extractRequestParams 提取 query、路径参数、指定请求头与请求体参数。
+// 请求体最多读取 MaxParamsSize 字节，读取的部分会放回，后续处理仍能读到完整请求体。
+func extractRequestParams(c *gin.Context, opts RequestLogOptions) requestParams {
+	p := requestParams{Params: valuesToMap(c.Request.URL.Query())}
+
+	if len(c.Params) > 0 {
+		p.PathParams = make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			p.PathParams[param.Key] = param.Value
+		}
+	}
+
+	if len(opts.Headers) > 0 {
+		selected := http.Header{}
+		for _, name := range opts.Headers {
+			if values := c.Request.Header.Values(name); len(values) > 0 {
+				selected[http.CanonicalHeaderKey(name)] = values
 			}
 		}
-	case "POST":
-		contentType := c.Request.Header.Get("Content-Type")
-		if contentType == "application/json" {
-			params = parseJSONBody(c)
-		} else {
-			params = parseFormBody(c)
+		p.Headers = GetRedactor().Headers(selected)
+	}
+
+	switch c.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		for k, v := range extractBodyParams(c, opts.MaxParamsSize) {
+			p.Params[k] = v
+		}
+	}
+	return p
+}
+
+// valuesToMap 单值参数记录为字符串，重复参数记录为数组
+func valuesToMap(values map[string][]string) map[string]interface{} {
+	params := make(map[string]interface{}, len(values))
+	for key, v := range values {
+		switch len(v) {
+		case 0:
+		case 1:
+			params[key] = v[0]
+		default:
+			params[key] = v
 		}
 	}
 	return params
 }
 
-// parseJSONBody 解析 JSON 格式的 POST 请求体
-func parseJSONBody(c *gin.Context) map[string]interface{} {
-	params := make(map[string]interface{})
-	bodyBytes, err := io.ReadAll(c.Request.Body)
+// extractBodyParams 按 Content-Type 解析请求体
+func extractBodyParams(c *gin.Context, limit int) map[string]interface{} {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+	mediaType, mediaParams, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return parseFormBody(c, limit)
+	case mediaType == "multipart/form-data" && mediaParams["boundary"] != "":
+		return parseMultipartBody(c, mediaParams["boundary"], limit)
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return parseJSONBody(c, limit)
+	default:
+		return parseRawBody(c, mediaType, limit)
+	}
+}
+
+// parseJSONBody 解析 JSON 请求体，对象字段合并到参数中，其他 JSON 值与无法解析的内容记录在 body 中
+func parseJSONBody(c *gin.Context, limit int) map[string]interface{} {
+	bodyBytes, truncated, err := peekBody(c, limit)
 	if err != nil {
 		logger.Error("Failed to read request body:", err)
+		return nil
+	}
+	if truncated {
+		return map[string]interface{}{"body": fmt.Sprintf("%s...[truncated]", bodyBytes)}
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return map[string]interface{}{"body": string(bodyBytes)}
+	}
+	if params, ok := body.(map[string]interface{}); ok {
 		return params
 	}
+	return map[string]interface{}{"body": body}
+}
 
-	if err := json.Unmarshal(bodyBytes, &params); err != nil {
-		logger.Error("Failed to parse JSON data:", err)
+// parseRawBody 文本请求体按字符串记录，二进制内容只记录长度
+func parseRawBody(c *gin.Context, mediaType string, limit int) map[string]interface{} {
+	bodyBytes, truncated, err := peekBody(c, limit)
+	if err != nil {
+		logger.Error("Failed to read request body:", err)
+		return nil
+	}
+	if len(bodyBytes) == 0 {
+		return nil
+	}
+	if !utf8.Valid(bodyBytes) {
+		return map[string]interface{}{"body": fmt.Sprintf("[binary %s]", mediaType)}
+	}
+	if truncated {
+		return map[string]interface{}{"body": fmt.Sprintf("%s...[truncated]", bodyBytes)}
+	}
+	return map[string]interface{}{"body": string(bodyBytes)}
+}
+
+// peekBody 最多读取 limit 字节并放回请求体，truncated 表示请求体超过 limit
+func peekBody(c *gin.Context, limit int) ([]byte, bool, error) {
+	body := c.Request.Body
+	bodyBytes, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		// 保留读取错误（如超过请求体上限），交给后续处理返回
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(bodyBytes), errReader{err: err}))
+		return nil, false, err
 	}
 
 	// 重置请求体以便后续处理中使用
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(bodyBytes), body), body}
+
+	if len(bodyBytes) > limit {
+		return bodyBytes[:limit], true, nil
+	}
+	return bodyBytes, false, nil
+}
+
+// errReader 读取时始终返回指定错误
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// parseFormBody 解析表单格式的请求体，只记录请求体中的字段，query 已单独提取。
+// 不调用 ParseForm，最多读取 limit 字节，超出部分丢弃最后一个不完整的字段
+func parseFormBody(c *gin.Context, limit int) map[string]interface{} {
+	bodyBytes, truncated, err := peekBody(c, limit)
+	if err != nil {
+		logger.Error("Failed to read request body:", err)
+		return nil
+	}
+	if truncated {
+		if i := bytes.LastIndexByte(bodyBytes, '&'); i >= 0 {
+			bodyBytes = bodyBytes[:i]
+		} else {
+			bodyBytes = nil
+		}
+	}
+	values, err := url.ParseQuery(string(bodyBytes))
+	if err != nil {
+		return map[string]interface{}{"body": "[invalid form]"}
+	}
+	params := valuesToMap(values)
+	if truncated {
+		params["form_truncated"] = true
+	}
 	return params
 }
 
-// parseFormBody 解析表单格式的 POST 请求体
-func parseFormBody(c *gin.Context) map[string]interface{} {
-	params := make(map[string]interface{})
-	if err := c.Request.ParseForm(); err != nil {
-		logger.Error("Failed to parse form data:", err)
-		return params
+// parseMultipartBody 只记录字段名与文件名，不记录字段值与文件内容。
+// 只解析前 limit 字节中的 part 头，不缓存上传内容，处理函数仍可使用 MultipartReader 流式读取
+func parseMultipartBody(c *gin.Context, boundary string, limit int) map[string]interface{} {
+	bodyBytes, truncated, err := peekBody(c, limit)
+	if err != nil {
+		logger.Error("Failed to read request body:", err)
+		return nil
 	}
 
-	for key, values := range c.Request.Form {
-		if len(values) > 0 {
-			params[key] = values[0]
+	fields := []string{}
+	files := map[string]interface{}{}
+	reader := multipart.NewReader(bytes.NewReader(bodyBytes), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			// 截断处的 part 无法读到结尾，已读到的 part 头照常记录
+			truncated = truncated || err != io.EOF
+			break
 		}
+		name := part.FormName()
+		if part.FileName() == "" {
+			fields = append(fields, name)
+			continue
+		}
+		meta, _ := files[name].([]map[string]interface{})
+		files[name] = append(meta, map[string]interface{}{
+			"filename":     part.FileName(),
+			"content_type": part.Header.Get("Content-Type"),
+		})
+	}
+	sort.Strings(fields)
+
+	params := map[string]interface{}{"multipart_fields": fields, "multipart_files": files}
+	if truncated {
+		params["multipart_truncated"] = true
 	}
 	return params
 }
@@ -135,9 +466,8 @@ func parseFormBody(c *gin.Context) map[string]interface{} {
 // GinRecovery 是一个用于捕获 panic 并记录日志的中间件
 func GinRecovery() gin.HandlerFunc {
 	return gin.CustomRecoveryWithWriter(os.Stderr, func(c *gin.Context, recovered interface{}) {
-		traceID, _ := c.Get("trace-id")
 		logger.WithFields(logrus.Fields{
-			"trace_id": traceID,
+			"trace-id": ctxkeys.TraceId(c),
 			"error":    recovered,
 			"path":     c.Request.URL.Path,
 			"method":   c.Request.Method,
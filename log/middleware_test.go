@@ -0,0 +1,192 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestLoggerOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := captureLogger(t)
+
+	opts := DefaultRequestLogOptions()
+	opts.ExcludePaths = []string{"/metrics", "/static/*"}
+	opts.MaxBodySize = 8
+	opts.SuccessSampleRate = 0
+	opts.SlowThreshold = 30 * time.Millisecond
+
+	r := gin.New()
+	r.Use(RequestLoggerWithOptions(opts))
+	r.GET("/static/app.js", func(c *gin.Context) { c.String(http.StatusOK, "console.log(1)") })
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "fine") })
+	r.GET("/fail", func(c *gin.Context) { c.JSON(http.StatusBadRequest, gin.H{"message": "bad request body"}) })
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		c.String(http.StatusOK, "slow")
+	})
+	r.GET("/file", func(c *gin.Context) {
+		c.Data(http.StatusInternalServerError, "application/octet-stream", []byte("binary"))
+	})
+
+	serve := func(path string) string {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?q=1", nil))
+		return buf.String()
+	}
+
+	if out := serve("/static/app.js"); out != "" {
+		t.Fatalf("excluded path logged: %s", out)
+	}
+	// 采样率为 0 时成功请求不记录
+	if out := serve("/ok"); out != "" {
+		t.Fatalf("unsampled success logged: %s", out)
+	}
+	out := serve("/fail")
+	if !strings.Contains(out, "request completed") || !strings.Contains(out, `[truncated`) || !strings.Contains(out, `"q":"1"`) {
+		t.Fatalf("error request: %s", out)
+	}
+	if out := serve("/slow"); !strings.Contains(out, "slow request") {
+		t.Fatalf("slow request: %s", out)
+	}
+	if out := serve("/file"); !strings.Contains(out, "[omitted application/octet-stream]") || strings.Contains(out, "binary") {
+		t.Fatalf("binary body logged: %s", out)
+	}
+}
+
+func TestRequestParamsCapture(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := captureLogger(t)
+
+	opts := DefaultRequestLogOptions()
+	opts.MaxParamsSize = 32
+	opts.Headers = []string{"X-Client", "Authorization"}
+
+	r := gin.New()
+	r.Use(RequestLoggerWithOptions(opts))
+	var received string
+	handler := func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		received = string(data)
+		c.Status(http.StatusNoContent)
+	}
+	r.PUT("/orders/:id", handler)
+	r.PATCH("/orders/:id", handler)
+
+	start := func(req *http.Request) map[string]interface{} {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &data); err != nil {
+			t.Fatalf("decode %q: %v", buf.String(), err)
+		}
+		return data
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/orders/42?tag=a&tag=b", strings.NewReader(`{"amount":10}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-Client", "ios")
+	req.Header.Set("Authorization", "Bearer secret")
+	data := start(req)
+	params := data["params"].(map[string]interface{})
+	if params["amount"] != float64(10) || len(params["tag"].([]interface{})) != 2 {
+		t.Fatalf("params %v", params)
+	}
+	if data["path_params"].(map[string]interface{})["id"] != "42" {
+		t.Fatalf("path params %v", data["path_params"])
+	}
+	headers := data["headers"].(map[string]interface{})
+	if headers["X-Client"] != "ios" || headers["Authorization"] != fullMask {
+		t.Fatalf("headers %v", headers)
+	}
+	if received != `{"amount":10}` {
+		t.Fatalf("handler body %q", received)
+	}
+
+	// 超过上限的请求体截断记录，处理函数仍读到完整内容
+	long := strings.Repeat("x", 100)
+	req = httptest.NewRequest(http.MethodPatch, "/orders/1", strings.NewReader(long))
+	req.Header.Set("Content-Type", "text/plain")
+	data = start(req)
+	if body := data["params"].(map[string]interface{})["body"].(string); !strings.HasSuffix(body, "[truncated]") || len(body) > 50 {
+		t.Fatalf("raw body %q", body)
+	}
+	if received != long {
+		t.Fatalf("handler body length %d", len(received))
+	}
+
+	// 表单只读取 MaxParamsSize 字节，处理函数仍能读到完整请求体
+	form := "name=bob&note=" + strings.Repeat("y", 40)
+	req = httptest.NewRequest(http.MethodPatch, "/orders/1", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data = start(req)
+	params = data["params"].(map[string]interface{})
+	if params["name"] != "bob" || params["note"] != nil || params["form_truncated"] != true {
+		t.Fatalf("form params %v", params)
+	}
+	if received != form {
+		t.Fatalf("handler form body %q", received)
+	}
+}
+
+func TestRequestParamsMultipart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := captureLogger(t)
+
+	r := gin.New()
+	r.Use(RequestLoggerWithOptions(DefaultRequestLogOptions()))
+	var parts []string
+	r.POST("/upload", func(c *gin.Context) {
+		// 日志中间件不能提前解析表单，否则 MultipartReader 会失败
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			parts = append(parts, part.FormName()+"="+string(data))
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	_ = mw.WriteField("nickname", "bob")
+	fw, _ := mw.CreateFormFile("avatar", "a.png")
+	_, _ = fw.Write([]byte("png-bytes"))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &data); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	params := data["params"].(map[string]interface{})
+	files := params["multipart_files"].(map[string]interface{})["avatar"].([]interface{})
+	if params["multipart_fields"].([]interface{})[0] != "nickname" || files[0].(map[string]interface{})["filename"] != "a.png" {
+		t.Fatalf("multipart %v", params)
+	}
+	if strings.Contains(buf.String(), "bob") || strings.Contains(buf.String(), "png-bytes") {
+		t.Fatalf("multipart values logged: %s", buf.String())
+	}
+	if w.Code != http.StatusNoContent || strings.Join(parts, ",") != "nickname=bob,avatar=png-bytes" {
+		t.Fatalf("handler stream %d %v", w.Code, parts)
+	}
+}
@@ -0,0 +1,405 @@
+package log
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 打码方式
+const (
+	MaskFull    = "full"    // 整体替换为 ******
+	MaskPartial = "partial" // 首尾各保留三分之一，最多 4 位
+	MaskHash    = "hash"    // 替换为 sha256 前 16 位，便于关联同一个值
+)
+
+const fullMask = "******"
+
+// RedactRule 脱敏规则，Fields/Paths 命中的值整体打码，Pattern 只打码匹配到的部分
+type RedactRule struct {
+	Fields  []string // 字段名，任意层级匹配，忽略大小写与 _ -
+	Paths   []string // JSONPath，相对于每个日志字段的值（如 params），支持 $.a.b、$.a[*].b、$.a[0]、$['a']
+	Pattern string   // 正则，作用于字符串值
+	Luhn    bool     // Pattern 匹配的内容需通过 Luhn 校验才打码，用于银行卡号，避免误伤订单号等长数字
+	Mask    string   // full/partial/hash，默认 full
+}
+
+// DefaultRedactRules 默认脱敏规则：密码、token、证件号整体打码，手机号、邮箱、银行卡号部分打码
+var DefaultRedactRules = []RedactRule{
+	{Fields: []string{"password", "passwd", "pwd", "secret", "token", "access_token", "refresh_token",
+		"authorization", "cookie", "set-cookie", "client_secret", "id_card", "id_no"}, Mask: MaskFull},
+	{Fields: []string{"phone", "mobile", "email", "card_no", "bank_card"}, Mask: MaskPartial},
+	{Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, Mask: MaskPartial},
+	{Pattern: `\b1[3-9]\d{9}\b`, Mask: MaskPartial},
+	{Pattern: `\b\d{15,19}\b`, Luhn: true, Mask: MaskPartial},
+}
+
+// Redactor 按规则脱敏日志字段、请求参数、请求头与响应体
+type Redactor struct {
+	fields   map[string]string
+	paths    []redactPath
+	patterns []redactPattern
+}
+
+type redactPath struct {
+	segments []string // 字段名，"*" 匹配任意数组下标，数字匹配指定下标
+	mask     string
+}
+
+type redactPattern struct {
+	re   *regexp.Regexp
+	luhn bool
+	mask string
+}
+
+// NewRedactor 编译脱敏规则
+func NewRedactor(rules []RedactRule) (*Redactor, error) {
+	r := &Redactor{fields: map[string]string{}}
+	for _, rule := range rules {
+		mask := rule.Mask
+		switch mask {
+		case "":
+			mask = MaskFull
+		case MaskFull, MaskPartial, MaskHash:
+		default:
+			return nil, fmt.Errorf("unknown redact mask %q", rule.Mask)
+		}
+
+		for _, field := range rule.Fields {
+			r.fields[normalizeField(field)] = mask
+		}
+		for _, path := range rule.Paths {
+			segments, err := parseJSONPath(path)
+			if err != nil {
+				return nil, err
+			}
+			r.paths = append(r.paths, redactPath{segments: segments, mask: mask})
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redact pattern %q: %w", rule.Pattern, err)
+			}
+			r.patterns = append(r.patterns, redactPattern{re: re, luhn: rule.Luhn, mask: mask})
+		}
+	}
+	return r, nil
+}
+
+var redactor atomic.Value
+
+func init() {
+	r, err := NewRedactor(DefaultRedactRules)
+	if err != nil {
+		panic(err)
+	}
+	redactor.Store(r)
+}
+
+// SetRedactor 替换全局脱敏规则，传 nil 时关闭脱敏
+func SetRedactor(r *Redactor) {
+	if r == nil {
+		r = &Redactor{}
+	}
+	redactor.Store(r)
+}
+
+// GetRedactor 返回全局脱敏规则
+func GetRedactor() *Redactor {
+	return redactor.Load().(*Redactor)
+}
+
+// Value 返回脱敏后的副本，map/slice 逐层处理，结构体按 JSON 字段处理
+func (r *Redactor) Value(v interface{}) interface{} {
+	if r.empty() {
+		return v
+	}
+	return r.walk(v, []string{}, true)
+}
+
+// String 对字符串脱敏；内容是 JSON 对象或数组时按字段规则处理
+func (r *Redactor) String(s string) string {
+	if r.empty() {
+		return s
+	}
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil {
+			if data, err := json.Marshal(r.walk(parsed, []string{}, true)); err == nil {
+				return string(data)
+			}
+		}
+	}
+	return r.maskPatterns(s)
+}
+
+// Headers 返回脱敏后的请求头，单值请求头展开为字符串
+func (r *Redactor) Headers(h http.Header) map[string]interface{} {
+	headers := make(map[string]interface{}, len(h))
+	for key, values := range h {
+		if mask, ok := r.fields[normalizeField(key)]; ok {
+			headers[key] = applyMask(strings.Join(values, ","), mask)
+			continue
+		}
+		masked := make([]string, len(values))
+		for i, v := range values {
+			masked[i] = r.maskPatterns(v)
+		}
+		if len(masked) == 1 {
+			headers[key] = masked[0]
+		} else {
+			headers[key] = masked
+		}
+	}
+	return headers
+}
+
+// Fields 对日志字段脱敏，trace-id、latency 等内部字段不处理
+func (r *Redactor) Fields(fields logrus.Fields) {
+	if r.empty() {
+		return
+	}
+	for key, value := range fields {
+		if _, skip := redactSkipFields[key]; skip {
+			continue
+		}
+		if mask, ok := r.fields[normalizeField(key)]; ok {
+			fields[key] = applyMask(fmt.Sprint(value), mask)
+			continue
+		}
+		fields[key] = r.walk(value, []string{}, true)
+	}
+}
+
+var redactSkipFields = map[string]struct{}{
+	"trace-id": {}, "method": {}, "path": {}, "status_code": {}, "latency": {}, "client_ip": {},
+	"module": {}, "app_name": {}, "version": {}, "host_name": {}, callStackField: {}, ErrorClassField: {},
+}
+
+func (r *Redactor) empty() bool {
+	return r == nil || (len(r.fields) == 0 && len(r.paths) == 0 && len(r.patterns) == 0)
+}
+
+// walk 递归处理并返回副本，path 为当前 JSON 路径；root 为 true 时 JSON 字符串按文档解析
+func (r *Redactor) walk(v interface{}, path []string, root bool) interface{} {
+	if mask, ok := r.matchPath(path); ok {
+		return applyMask(fmt.Sprint(v), mask)
+	}
+
+	switch value := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return v
+	case string:
+		if root {
+			return r.String(value)
+		}
+		return r.maskPatterns(value)
+	case error:
+		return r.maskPatterns(value.Error())
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			if mask, ok := r.fields[normalizeField(k)]; ok {
+				out[k] = applyMask(fmt.Sprint(item), mask)
+				continue
+			}
+			out[k] = r.walk(item, appendPath(path, k), false)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = r.walk(item, appendPath(path, strconv.Itoa(i)), false)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			out[k] = item
+		}
+		return r.walk(out, path, false)
+	case []string:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = item
+		}
+		return r.walk(out, path, false)
+	default:
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return v
+		}
+		if stringer, ok := v.(fmt.Stringer); ok {
+			return r.maskPatterns(stringer.String())
+		}
+		// 结构体等其他类型按 JSON 转换后处理
+		data, err := json.Marshal(value)
+		if err != nil {
+			return v
+		}
+		var parsed interface{}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return v
+		}
+		return r.walk(parsed, path, false)
+	}
+}
+
+func appendPath(path []string, segment string) []string {
+	next := make([]string, len(path)+1)
+	copy(next, path)
+	next[len(path)] = segment
+	return next
+}
+
+func (r *Redactor) matchPath(path []string) (string, bool) {
+	if len(path) == 0 {
+		return "", false
+	}
+	for _, p := range r.paths {
+		if len(p.segments) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range p.segments {
+			if segment == "*" {
+				if _, err := strconv.Atoi(path[i]); err != nil {
+					matched = false
+					break
+				}
+				continue
+			}
+			if segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return p.mask, true
+		}
+	}
+	return "", false
+}
+
+func (r *Redactor) maskPatterns(s string) string {
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+			if p.luhn && !luhnValid(m) {
+				return m
+			}
+			return applyMask(m, p.mask)
+		})
+	}
+	return s
+}
+
+func applyMask(s, mask string) string {
+	switch mask {
+	case MaskPartial:
+		runes := []rune(s)
+		keep := len(runes) / 3
+		if keep > 4 {
+			keep = 4
+		}
+		if keep == 0 {
+			return fullMask
+		}
+		return string(runes[:keep]) + strings.Repeat("*", len(runes)-2*keep) + string(runes[len(runes)-keep:])
+	case MaskHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	default:
+		return fullMask
+	}
+}
+
+// luhnValid 银行卡号校验位
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func normalizeField(field string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(field))
+}
+
+// parseJSONPath 解析 JSONPath 子集：$.a.b、$.a[*].b、$.a[0]、$['a']
+func parseJSONPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("redact path %q must start with $", path)
+	}
+	var segments []string
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("redact path %q: empty field", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("redact path %q: missing ]", path)
+			}
+			segments = append(segments, strings.Trim(rest[1:end], `'"`))
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("redact path %q: unexpected %q", path, rest[0])
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("redact path %q: empty path", path)
+	}
+	return segments, nil
+}
+
+// redactHook 在其他 hook 与终端输出之前对日志字段与日志内容脱敏
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	r := GetRedactor()
+	if r.empty() {
+		return nil
+	}
+	r.Fields(entry.Data)
+	// Infof 等格式化后的内容没有字段名，只能按正则规则处理
+	entry.Message = r.maskPatterns(entry.Message)
+	return nil
+}
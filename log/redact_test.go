@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor(append(DefaultRedactRules,
+		RedactRule{Paths: []string{"$.items[*].sku_secret"}, Mask: MaskHash},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]interface{}{
+		"userName": "alice",
+		"Password": "p@ss",
+		"mobile":   "13812345678",
+		"profile":  map[string]interface{}{"access-token": "abc", "note": "mail me at alice@example.com"},
+		"items":    []interface{}{map[string]interface{}{"sku_secret": "s1", "qty": 2}},
+		"order_no": "202410190000000001",
+	}
+	got := r.Value(params).(map[string]interface{})
+	if got["userName"] != "alice" || got["Password"] != fullMask || got["mobile"] != "138*****678" {
+		t.Fatalf("unexpected %v", got)
+	}
+	profile := got["profile"].(map[string]interface{})
+	if profile["access-token"] != fullMask || strings.Contains(profile["note"].(string), "alice@example.com") {
+		t.Fatalf("unexpected profile %v", profile)
+	}
+	item := got["items"].([]interface{})[0].(map[string]interface{})
+	if !strings.HasPrefix(item["sku_secret"].(string), "sha256:") || item["qty"] != 2 {
+		t.Fatalf("unexpected item %v", item)
+	}
+	// 不通过 Luhn 校验的长数字不打码
+	if got["order_no"] != "202410190000000001" || params["Password"] != "p@ss" {
+		t.Fatalf("order_no %v / original modified %v", got["order_no"], params["Password"])
+	}
+	if masked := r.String("card 4111111111111111 paid"); strings.Contains(masked, "4111111111111111") {
+		t.Fatalf("card not masked: %s", masked)
+	}
+
+	body := r.String(`{"token":"t","data":{"phone":"13812345678"}}`)
+	if strings.Contains(body, `"t"`) || strings.Contains(body, "13812345678") {
+		t.Fatalf("body not masked: %s", body)
+	}
+
+	headers := r.Headers(http.Header{"Authorization": {"Bearer x"}, "X-Trace": {"a", "b"}})
+	if headers["Authorization"] != fullMask || len(headers["X-Trace"].([]string)) != 2 {
+		t.Fatalf("unexpected headers %v", headers)
+	}
+
+	if _, err := NewRedactor([]RedactRule{{Paths: []string{"user.id"}}}); err == nil {
+		t.Fatal("expected path error")
+	}
+}
+
+func TestRedactHook(t *testing.T) {
+	buf := captureLogger(t)
+	Ctx(context.Background()).With("password", "secret").Info("login", map[string]interface{}{"phone": "13812345678"})
+	data := lastLine(t, buf)
+	if data["password"] != fullMask || strings.Contains(buf.String(), "13812345678") {
+		t.Fatalf("fields not redacted: %s", buf.String())
+	}
+
+	// 格式化到日志内容中的敏感信息同样按正则规则处理
+	Ctx(context.Background()).Infof("send sms to %s", "13812345678")
+	GetLogger().Warnf("user %s login failed", "13912345678")
+	if strings.Contains(buf.String(), "13812345678") || strings.Contains(buf.String(), "13912345678") {
+		t.Fatalf("message not redacted: %s", buf.String())
+	}
+}
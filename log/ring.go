@@ -0,0 +1,110 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 队列满时的处理策略
+const (
+	OverflowBlock  = "block"  // 阻塞调用方直到有空位，不丢日志
+	OverflowDrop   = "drop"   // 丢弃新日志
+	OverflowSample = "sample" // 队列过半后低于 Warn 的日志按 SampleRate 抽样，队列满时 Warn 及以上阻塞、其余丢弃
+)
+
+// ringBuffer 定长环形队列，单个消费者批量取出
+type ringBuffer struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+
+	items  []*logrus.Entry
+	head   int
+	size   int
+	busy   bool // 消费者正在写出已取出的一批
+	closed bool
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	r := &ringBuffer{items: make([]*logrus.Entry, capacity)}
+	r.notEmpty = sync.NewCond(&r.mu)
+	r.notFull = sync.NewCond(&r.mu)
+	r.idle = sync.NewCond(&r.mu)
+	return r
+}
+
+// push 写入一条，队列满且 block 为 false 时返回 false；关闭后写入返回 false
+func (r *ringBuffer) push(entry *logrus.Entry, block bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.size == len(r.items) && !r.closed {
+		if !block {
+			return false
+		}
+		r.notFull.Wait()
+	}
+	if r.closed {
+		return false
+	}
+
+	r.items[(r.head+r.size)%len(r.items)] = entry
+	r.size++
+	r.notEmpty.Signal()
+	return true
+}
+
+// popAll 阻塞到有数据后取出全部，关闭且已取空时返回 nil
+func (r *ringBuffer) popAll(dst []*logrus.Entry) []*logrus.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.busy = false
+	if r.size == 0 {
+		r.idle.Broadcast()
+	}
+	for r.size == 0 && !r.closed {
+		r.notEmpty.Wait()
+	}
+	if r.size == 0 {
+		return nil
+	}
+
+	for r.size > 0 {
+		dst = append(dst, r.items[r.head])
+		r.items[r.head] = nil
+		r.head = (r.head + 1) % len(r.items)
+		r.size--
+	}
+	r.busy = true
+	r.notFull.Broadcast()
+	return dst
+}
+
+// len 当前排队条数
+func (r *ringBuffer) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.size
+}
+
+// flush 等待队列中已有的日志全部写出
+func (r *ringBuffer) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for (r.size > 0 || r.busy) && !r.closed {
+		r.idle.Wait()
+	}
+}
+
+// close 不再接受写入，消费者取完剩余数据后退出
+func (r *ringBuffer) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.notEmpty.Broadcast()
+	r.notFull.Broadcast()
+	r.idle.Broadcast()
+}
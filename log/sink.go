@@ -0,0 +1,360 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/global"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 日志输出类型
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkHTTP   = "http"
+)
+
+// 日志格式
+const (
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+	FormatConsole = "console" // 带颜色的文本，用于本地终端
+)
+
+// Sink 日志输出目标，Write 收到的是已格式化的一条日志
+type Sink interface {
+	Write(level logrus.Level, p []byte) error
+	Close() error
+}
+
+// SinkConfig 日志输出配置
+type SinkConfig struct {
+	Type       string            // stdout/file/syslog/http
+	Name       string            // 指标中的 sink 标签，默认与 Type 相同
+	Level      logrus.Level      // 只输出该级别及更严重的日志，零值时输出全部
+	Format     string            // json/logfmt/console，默认 json
+	BufferSize int               // 异步环形队列长度，0 时同步写入
+	Overflow   string            // 队列满时的策略：block/drop/sample，默认 block
+	SampleRate int               // sample 策略下低级别日志每 SampleRate 条保留 1 条，默认 10
+	Dir        string            // file：日志目录
+	File       string            // file：文件名
+	MaxSize    int               // file：单个文件大小（MB）
+	MaxBackups int               // file：保留的旧文件个数
+	MaxAge     int               // file：旧文件保留天数
+	Compress   bool              // file：是否压缩旧文件
+	Network    string            // syslog：udp/tcp，为空时使用本机 syslog
+	Address    string            // syslog：地址
+	Tag        string            // syslog：tag，默认使用 app_name
+	URL        string            // http：接收地址
+	Headers    map[string]string // http：附加请求头，如鉴权
+	BatchSize  int               // http：每批条数，默认 100
+	FlushEvery time.Duration     // http：最长发送间隔，默认 1s
+	Timeout    time.Duration     // http：请求超时，默认 5s
+}
+
+var (
+	sinkDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_sink_dropped_total",
+			Help: "Log entries dropped because the sink queue was full or closed",
+		},
+		[]string{"sink"},
+	)
+	sinkErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_sink_write_errors_total",
+			Help: "Log sink write failures",
+		},
+		[]string{"sink"},
+	)
+	sinkSampled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_sink_sampled_total",
+			Help: "Log entries skipped by the sample overflow policy",
+		},
+		[]string{"sink"},
+	)
+	sinkQueueLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "log_sink_queue_length",
+			Help: "Log entries waiting in the sink queue",
+		},
+		[]string{"sink"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(sinkDropped)
+	prometheus.MustRegister(sinkErrors)
+	prometheus.MustRegister(sinkSampled)
+	prometheus.MustRegister(sinkQueueLength)
+}
+
+// NewSink 按配置创建日志输出
+func NewSink(conf SinkConfig) (Sink, error) {
+	switch conf.Type {
+	case SinkStdout:
+		return writerSink{w: os.Stdout}, nil
+	case SinkFile:
+		defaultConf := DefaultConfig()
+		if conf.Dir == "" {
+			conf.Dir = defaultConf.Dir
+		}
+		if conf.File == "" {
+			conf.File = defaultConf.File
+		}
+		if err := os.MkdirAll(conf.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("create log directory %s: %w", conf.Dir, err)
+		}
+		return &fileSink{w: &lumberjack.Logger{
+			Filename:   filepath.Join(conf.Dir, conf.File),
+			MaxSize:    conf.MaxSize,
+			MaxBackups: conf.MaxBackups,
+			MaxAge:     conf.MaxAge,
+			Compress:   conf.Compress,
+		}}, nil
+	case SinkSyslog:
+		return newSyslogSink(conf)
+	case SinkHTTP:
+		return newHTTPSink(conf)
+	default:
+		return nil, fmt.Errorf("unknown log sink type %q", conf.Type)
+	}
+}
+
+// NewFormatter 按格式名创建 formatter
+func NewFormatter(format, stackFilter string) (logrus.Formatter, error) {
+	switch format {
+	case "", FormatJSON:
+		return &OrderedJSONFormatter{TimestampFormat: time.RFC3339, StackFilter: stackFilter}, nil
+	case FormatLogfmt:
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true, TimestampFormat: time.RFC3339}, nil
+	case FormatConsole:
+		return &logrus.TextFormatter{ForceColors: true, FullTimestamp: true, TimestampFormat: time.RFC3339}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+type writerSink struct {
+	w *os.File
+}
+
+func (s writerSink) Write(_ logrus.Level, p []byte) error {
+	_, err := s.w.Write(p)
+	return err
+}
+
+func (s writerSink) Close() error { return nil }
+
+type fileSink struct {
+	w *lumberjack.Logger
+}
+
+func (s *fileSink) Write(_ logrus.Level, p []byte) error {
+	_, err := s.w.Write(p)
+	return err
+}
+
+func (s *fileSink) Close() error { return s.w.Close() }
+
+// sinkHook 将日志按 sink 的级别和格式写出。
+// 配置了 BufferSize 时日志进入环形队列，由后台 goroutine 格式化并写出，调用方只复制字段和采集调用栈。
+type sinkHook struct {
+	name      string
+	sink      Sink
+	levels    []logrus.Level
+	formatter logrus.Formatter
+
+	buffer     *ringBuffer // nil 时同步写入
+	capacity   int
+	overflow   string
+	sampleRate uint64
+	sampled    uint64
+	wg         sync.WaitGroup
+
+	// InitLogger 替换 hook 后仍可能有并发的 Fire，关闭后的写入直接丢弃
+	mu     sync.RWMutex
+	closed bool
+}
+
+// flusher 自带缓冲的 sink（如 http）实现该接口，Flush 时一并发送
+type flusher interface {
+	Flush() error
+}
+
+func newSinkHook(conf SinkConfig, stackFilter string) (*sinkHook, error) {
+	switch conf.Overflow {
+	case "", OverflowBlock, OverflowDrop, OverflowSample:
+	default:
+		return nil, fmt.Errorf("unknown log overflow policy %q", conf.Overflow)
+	}
+	formatter, err := NewFormatter(conf.Format, stackFilter)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := NewSink(conf)
+	if err != nil {
+		return nil, err
+	}
+	return newSinkHookWith(conf, sink, formatter), nil
+}
+
+func newSinkHookWith(conf SinkConfig, sink Sink, formatter logrus.Formatter) *sinkHook {
+	name := conf.Name
+	if name == "" {
+		name = conf.Type
+	}
+	level := conf.Level
+	if level == logrus.PanicLevel {
+		level = logrus.TraceLevel
+	}
+	overflow := conf.Overflow
+	if overflow == "" {
+		overflow = OverflowBlock
+	}
+	sampleRate := conf.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 10
+	}
+
+	h := &sinkHook{
+		name:       name,
+		sink:       sink,
+		levels:     logrus.AllLevels[:level+1],
+		formatter:  formatter,
+		capacity:   conf.BufferSize,
+		overflow:   overflow,
+		sampleRate: uint64(sampleRate),
+	}
+	if conf.BufferSize > 0 {
+		h.buffer = newRingBuffer(conf.BufferSize)
+		h.wg.Add(1)
+		go h.run()
+	}
+	return h
+}
+
+func (h *sinkHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *sinkHook) Fire(entry *logrus.Entry) error {
+	if !entryEnabled(entry) {
+		return nil
+	}
+	// 复制一份，其他 hook 与异步格式化互不影响
+	snapshot := *entry
+	snapshot.Data = make(logrus.Fields, len(entry.Data)+len(global.LogPreInfo)+1)
+	for k, v := range entry.Data {
+		snapshot.Data[k] = v
+	}
+	// 全局字段每次写入时读取，system 配置加载后设置的 app_name 等字段立即生效
+	for k, v := range global.LogPreInfo {
+		snapshot.Data[k] = v
+	}
+	// 调用栈只能在调用方 goroutine 中采集
+	if f, ok := h.formatter.(*OrderedJSONFormatter); ok && needStack(entry.Level) {
+		snapshot.Data[callStackField] = f.captureStack()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return nil
+	}
+	if h.buffer == nil {
+		h.write(&snapshot)
+		return nil
+	}
+
+	if !h.enqueue(&snapshot) {
+		sinkDropped.WithLabelValues(h.name).Inc()
+	}
+	sinkQueueLength.WithLabelValues(h.name).Set(float64(h.buffer.len()))
+	// Fatal/Panic 之后进程可能退出，立即写出
+	if entry.Level <= logrus.FatalLevel {
+		h.buffer.flush()
+	}
+	return nil
+}
+
+// enqueue 按溢出策略写入队列，返回 false 表示已丢弃
+func (h *sinkHook) enqueue(entry *logrus.Entry) bool {
+	switch h.overflow {
+	case OverflowDrop:
+		return h.buffer.push(entry, false)
+	case OverflowSample:
+		important := entry.Level <= logrus.WarnLevel
+		if !important && h.buffer.len()*2 >= h.capacity {
+			if atomic.AddUint64(&h.sampled, 1)%h.sampleRate != 0 {
+				sinkSampled.WithLabelValues(h.name).Inc()
+				return true
+			}
+		}
+		return h.buffer.push(entry, important)
+	default:
+		return h.buffer.push(entry, true)
+	}
+}
+
+func (h *sinkHook) run() {
+	defer h.wg.Done()
+	var batch []*logrus.Entry
+	for {
+		batch = h.buffer.popAll(batch[:0])
+		if batch == nil {
+			return
+		}
+		for _, entry := range batch {
+			h.write(entry)
+		}
+		sinkQueueLength.WithLabelValues(h.name).Set(float64(h.buffer.len()))
+	}
+}
+
+func (h *sinkHook) write(entry *logrus.Entry) {
+	data, err := h.formatter.Format(entry)
+	if err == nil {
+		err = h.sink.Write(entry.Level, data)
+	}
+	if err != nil {
+		sinkErrors.WithLabelValues(h.name).Inc()
+	}
+}
+
+// Flush 等待队列中已有的日志写出
+func (h *sinkHook) Flush() error {
+	if h.buffer != nil {
+		h.buffer.flush()
+	}
+	if f, ok := h.sink.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close 写完队列中剩余的日志后关闭 sink
+func (h *sinkHook) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+
+	if h.buffer != nil {
+		h.buffer.close()
+	}
+	h.wg.Wait()
+	return h.sink.Close()
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// httpSink 批量发送日志，请求体为换行分隔的日志（NDJSON），可对接 Loki/Vector/Fluent Bit 等 HTTP 接收端
+type httpSink struct {
+	url       string
+	headers   map[string]string
+	client    *http.Client
+	batchSize int
+
+	mu    sync.Mutex
+	batch [][]byte
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newHTTPSink(conf SinkConfig) (Sink, error) {
+	if conf.URL == "" {
+		return nil, errors.New("http log sink requires url")
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 100
+	}
+	if conf.FlushEvery <= 0 {
+		conf.FlushEvery = time.Second
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 5 * time.Second
+	}
+
+	s := &httpSink{
+		url:       conf.URL,
+		headers:   conf.Headers,
+		client:    &http.Client{Timeout: conf.Timeout},
+		batchSize: conf.BatchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	name := conf.Name
+	if name == "" {
+		name = SinkHTTP
+	}
+	go s.loop(name, conf.FlushEvery)
+	return s, nil
+}
+
+// Write 攒批，达到 BatchSize 时立即发送
+func (s *httpSink) Write(_ logrus.Level, p []byte) error {
+	s.mu.Lock()
+	s.batch = append(s.batch, p)
+	full := len(s.batch) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Close 发送剩余日志
+func (s *httpSink) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
+
+func (s *httpSink) loop(name string, every time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				sinkErrors.WithLabelValues(name).Inc()
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Flush 立即发送当前批次
+func (s *httpSink) Flush() error {
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(bytes.Join(batch, nil)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("http log sink: status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package log
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/hyzx-go/common-b2c/global"
+	"github.com/sirupsen/logrus"
+)
+
+// syslogSink 按日志级别映射 syslog severity
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func newSyslogSink(conf SinkConfig) (Sink, error) {
+	tag := conf.Tag
+	if tag == "" {
+		tag, _ = global.LogPreInfo["app_name"].(string)
+	}
+	w, err := syslog.Dial(conf.Network, conf.Address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, fmt.Errorf("dial syslog: %w", err)
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Write(level logrus.Level, p []byte) error {
+	msg := string(p)
+	switch level {
+	case logrus.PanicLevel:
+		return s.w.Emerg(msg)
+	case logrus.FatalLevel:
+		return s.w.Crit(msg)
+	case logrus.ErrorLevel:
+		return s.w.Err(msg)
+	case logrus.WarnLevel:
+		return s.w.Warning(msg)
+	case logrus.InfoLevel:
+		return s.w.Info(msg)
+	default:
+		return s.w.Debug(msg)
+	}
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
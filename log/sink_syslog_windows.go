@@ -0,0 +1,9 @@
+//go:build windows
+
+package log
+
+import "errors"
+
+func newSyslogSink(SinkConfig) (Sink, error) {
+	return nil, errors.New("syslog log sink is not supported on windows")
+}
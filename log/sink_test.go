@@ -0,0 +1,146 @@
+package log
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	lines   []string
+	release chan struct{}
+}
+
+func (s *memorySink) Write(_ logrus.Level, p []byte) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, string(p))
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestSinkHookLevelAndFormat(t *testing.T) {
+	captureLogger(t)
+	sink := &memorySink{}
+	formatter, _ := NewFormatter(FormatLogfmt, "")
+	hook := newSinkHookWith(SinkConfig{Type: "memory", Level: logrus.WarnLevel, BufferSize: 16}, sink, formatter)
+	logger.AddHook(hook)
+
+	GetLogger().Info("skipped")
+	GetLogger().WithField("order_id", 7).Warn("kept")
+	if err := hook.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.lines) != 1 || !strings.Contains(sink.lines[0], "msg=kept") || !strings.Contains(sink.lines[0], "order_id=7") {
+		t.Fatalf("unexpected lines %q", sink.lines)
+	}
+	// 关闭后写入直接丢弃
+	GetLogger().Warn("after close")
+	if len(sink.lines) != 1 {
+		t.Fatal("write after close")
+	}
+}
+
+func TestSinkHookDrop(t *testing.T) {
+	captureLogger(t)
+	sink := &memorySink{release: make(chan struct{})}
+	formatter, _ := NewFormatter(FormatJSON, "")
+	hook := newSinkHookWith(SinkConfig{Type: "memory", Name: "drop-test", BufferSize: 1, Overflow: OverflowDrop}, sink, formatter)
+	logger.AddHook(hook)
+	before := testutil.ToFloat64(sinkDropped.WithLabelValues("drop-test"))
+
+	// 第一条被后台 goroutine 取走后阻塞在 Write，第二条占满队列，drop 策略下之后的全部丢弃
+	GetLogger().Info("first")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		GetLogger().Info("burst")
+	}
+	if dropped := testutil.ToFloat64(sinkDropped.WithLabelValues("drop-test")) - before; dropped != 4 {
+		t.Fatalf("dropped %v want 4", dropped)
+	}
+	close(sink.release)
+	_ = hook.Close()
+	if len(sink.lines) != 2 {
+		t.Fatalf("written %d want 2", len(sink.lines))
+	}
+}
+
+func TestSinkHookSample(t *testing.T) {
+	captureLogger(t)
+	sink := &memorySink{release: make(chan struct{})}
+	formatter, _ := NewFormatter(FormatJSON, "")
+	hook := newSinkHookWith(SinkConfig{Type: "memory", Name: "sample-test", BufferSize: 4, Overflow: OverflowSample, SampleRate: 3}, sink, formatter)
+	logger.AddHook(hook)
+
+	GetLogger().Info("first")
+	time.Sleep(20 * time.Millisecond)
+	// 队列过半后 Info 按 1/3 抽样，Warn 全部保留
+	for i := 0; i < 6; i++ {
+		GetLogger().Info("burst")
+	}
+	GetLogger().Warn("important")
+	close(sink.release)
+	_ = hook.Close()
+
+	var warns int
+	for _, line := range sink.lines {
+		if strings.Contains(line, "important") {
+			warns++
+			if !strings.Contains(line, `"call_stack"`) || !strings.Contains(line, "TestSinkHookSample") {
+				t.Fatalf("call stack not captured at caller: %s", line)
+			}
+		}
+	}
+	if warns != 1 || testutil.ToFloat64(sinkSampled.WithLabelValues("sample-test")) == 0 {
+		t.Fatalf("lines %d warns %d sampled %v", len(sink.lines), warns, testutil.ToFloat64(sinkSampled.WithLabelValues("sample-test")))
+	}
+}
+
+func TestHTTPSinkBatch(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lines []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		mu.Lock()
+		batches = append(batches, lines)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{Type: SinkHTTP, URL: server.URL, BatchSize: 2, FlushEvery: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		if err := sink.Write(logrus.InfoLevel, []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第三条在 Close 时发送
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[1][0] != "c" {
+		t.Fatalf("unexpected batches %q", batches)
+	}
+}
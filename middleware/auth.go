@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/auth"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/response"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthMode 路由认证方式
+type AuthMode int
+
+const (
+	// AuthPublic 不解析 token
+	AuthPublic AuthMode = iota
+	// AuthOptional 有 token 时校验并写入 claims，没有 token 时放行
+	AuthOptional
+	// AuthRequired 必须携带有效 token
+	AuthRequired
+)
+
+var (
+	defaultVerifier     *auth.Verifier
+	defaultVerifierOnce sync.Once
+)
+
+// getAuthConf 读取 auth 配置，未配置时使用 HS256 + Authorization: Bearer
+func getAuthConf() *config.AuthConf {
+	conf, err := config.GetParser().GetAuthConf()
+	if err != nil {
+		return &config.AuthConf{Algorithm: auth.HS256, Header: "Authorization", Scheme: "Bearer"}
+	}
+	return conf
+}
+
+// newVerifierFromConfig HS256 使用 system.auth_secret，RS256/ES256 从 jwks_file 读取公钥
+func newVerifierFromConfig(conf *config.AuthConf) (*auth.Verifier, error) {
+	options := auth.Options{
+		Algorithm: conf.Algorithm,
+		Issuer:    conf.Issuer,
+		Audience:  conf.Audience,
+		Leeway:    time.Duration(conf.Leeway) * time.Second,
+	}
+	switch conf.Algorithm {
+	case auth.HS256:
+		sys, err := config.GetParser().GetSystemConf()
+		if err != nil {
+			return nil, err
+		}
+		options.Secret = []byte(sys.AuthSecret)
+	default:
+		keys, err := auth.LoadJWKS(conf.JwksFile)
+		if err != nil {
+			return nil, err
+		}
+		options.Keys = keys
+	}
+	return auth.NewVerifier(options)
+}
+
+// getDefaultVerifier 全局共享的 token 校验器
+func getDefaultVerifier() *auth.Verifier {
+	defaultVerifierOnce.Do(func() {
+		verifier, err := newVerifierFromConfig(getAuthConf())
+		if err != nil {
+			panic(fmt.Errorf("auth verifier init failed: %w", err))
+		}
+		defaultVerifier = verifier
+	})
+	return defaultVerifier
+}
+
+// JWTAuth 按 auth 配置校验 token，挂在需要认证的路由组上
+func JWTAuth(mode AuthMode) gin.HandlerFunc {
+	if mode == AuthPublic {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return JWTAuthWithVerifier(getDefaultVerifier(), getAuthConf(), mode)
+}
+
+// JWTAuthWithVerifier 使用指定的校验器。
+// 校验通过后 claims 写入 gin.Context 与 c.Request.Context()，可通过 auth.FromContext 读取，
+// 同时写入 ContextUserIdKey/ContextRateTierKey 供限流使用，user_id/tenant_id 作为日志字段随请求日志输出。
+func JWTAuthWithVerifier(verifier *auth.Verifier, conf *config.AuthConf, mode AuthMode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if mode == AuthPublic {
+			c.Next()
+			return
+		}
+
+		token := extractToken(c, conf)
+		if token == "" {
+			if mode == AuthOptional {
+				c.Next()
+				return
+			}
+			response.FailWithStatus(http.StatusUnauthorized, response.Unauthorized, nil, c)
+			return
+		}
+
+		// 携带了 token 但无效时，可选认证同样拒绝，避免伪造身份静默降级
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			log.Ctx(c).Warn("jwt verify failed", err)
+			response.FailWithStatus(http.StatusUnauthorized, response.AuthenticationError, nil, c)
+			return
+		}
+
+		c.Set(auth.GinClaimsKey, claims)
+		if claims.UserId != "" {
+			c.Set(ContextUserIdKey, claims.UserId)
+		}
+		if claims.Tier != "" {
+			c.Set(ContextRateTierKey, claims.Tier)
+		}
+		c.Request = c.Request.WithContext(auth.WithClaims(c.Request.Context(), claims))
+
+		// 后续 log.Ctx(c) 打印的日志自动带上用户与租户
+		fields := log.Fields{}
+		if claims.UserId != "" {
+			fields["user_id"] = claims.UserId
+		}
+		if claims.TenantId != "" {
+			fields["tenant_id"] = claims.TenantId
+		}
+		if len(fields) > 0 {
+			ctxkeys.SetLogFields(c, fields)
+		}
+		c.Next()
+	}
+}
+
+func extractToken(c *gin.Context, conf *config.AuthConf) string {
+	header := conf.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	value := strings.TrimSpace(c.GetHeader(header))
+	if conf.Scheme == "" {
+		return value
+	}
+	if len(value) > len(conf.Scheme) && strings.EqualFold(value[:len(conf.Scheme)], conf.Scheme) && value[len(conf.Scheme)] == ' ' {
+		return strings.TrimSpace(value[len(conf.Scheme)+1:])
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/auth"
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+func TestJWTAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := []byte("secret")
+	verifier, err := auth.NewVerifier(auth.Options{Algorithm: auth.HS256, Secret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.AuthConf{Header: "Authorization", Scheme: "Bearer"}
+
+	r := gin.New()
+	handler := func(c *gin.Context) {
+		claims, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, claims.UserId+"/"+c.GetString(ContextRateTierKey))
+	}
+	r.GET("/required", JWTAuthWithVerifier(verifier, conf, AuthRequired), handler)
+	r.GET("/optional", JWTAuthWithVerifier(verifier, conf, AuthOptional), handler)
+
+	valid, _ := auth.SignHS256(map[string]interface{}{"uid": "u1", "tier": "vip", "exp": time.Now().Unix() + 60}, secret)
+	expired, _ := auth.SignHS256(map[string]interface{}{"uid": "u1", "exp": time.Now().Unix() - 60}, secret)
+
+	cases := []struct {
+		path   string
+		token  string
+		code   int
+		expect string
+	}{
+		{"/required", "", http.StatusUnauthorized, ""},
+		{"/required", "Bearer " + valid, http.StatusOK, "u1/vip"},
+		{"/required", "Bearer " + expired, http.StatusUnauthorized, ""},
+		{"/optional", "", http.StatusOK, "anonymous"},
+		{"/optional", "bearer " + valid, http.StatusOK, "u1/vip"},
+		{"/optional", "Bearer " + expired, http.StatusUnauthorized, ""},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest(http.MethodGet, cs.path, nil)
+		if cs.token != "" {
+			req.Header.Set("Authorization", cs.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != cs.code || (cs.expect != "" && w.Body.String() != cs.expect) {
+			t.Errorf("%s %q: %d %s", cs.path, cs.token, w.Code, w.Body.String())
+		}
+	}
+}
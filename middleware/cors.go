@@ -2,23 +2,163 @@ package middlewares
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+// Cors 读取 cors 配置，未配置时放行所有来源但不允许携带凭证（Cookie、Authorization 等）
 func Cors() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		method := c.Request.Method
+	conf, err := config.GetParser().GetCorsConf()
+	if err != nil {
+		conf = &config.CorsConf{
+			AllowOrigins:  []string{"*"},
+			AllowMethods:  []string{"POST", "GET", "OPTIONS"},
+			AllowHeaders:  []string{"Content-Type", "AccessToken", "X-CSRF-Token", "Authorization", "Token", "redis-token"},
+			ExposeHeaders: []string{"Content-Length", "Access-Control-Allow-Origin", "Access-Control-Allow-Headers", "Content-Type"},
+		}
+	}
+	return CorsWithConfig(conf)
+}
+
+// CorsWithConfig 使用指定配置的跨域中间件
+func CorsWithConfig(conf *config.CorsConf) gin.HandlerFunc {
+	cors := newCors(conf)
+	return cors.handle
+}
+
+type cors struct {
+	conf          *config.CorsConf
+	allowAll      bool
+	origins       []string
+	originRegex   []*regexp.Regexp
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+func newCors(conf *config.CorsConf) *cors {
+	c := &cors{
+		conf:          conf,
+		allowMethods:  strings.Join(conf.AllowMethods, ", "),
+		allowHeaders:  strings.Join(conf.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(conf.ExposeHeaders, ", "),
+	}
+	if conf.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(conf.MaxAge)
+	}
+	for _, origin := range conf.AllowOrigins {
+		if origin == "*" {
+			c.allowAll = true
+			continue
+		}
+		c.origins = append(c.origins, strings.ToLower(origin))
+	}
+	for _, expr := range conf.AllowOriginRegex {
+		c.originRegex = append(c.originRegex, regexp.MustCompile(config.AnchorOriginRegex(expr)))
+	}
+	return c
+}
+
+func (cs *cors) handle(c *gin.Context) {
+	// 响应随 Origin 变化，避免被共享缓存错误复用
+	c.Writer.Header().Add("Vary", "Origin")
+
+	origin := c.GetHeader("Origin")
+	preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+	if origin == "" {
+		c.Next()
+		return
+	}
+
+	if !cs.isOriginAllowed(origin) {
+		if preflight {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+		return
+	}
+
+	// "*" 放行所有来源时不允许携带凭证，否则任意站点都能以用户身份读取接口；
+	// CorsConf.Initialize 会拒绝该组合，这里兜底直接使用 CorsWithConfig 的场景
+	if cs.allowAll {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Headers", "Content-Type,AccessToken,X-CSRF-Token, Authorization, Token, redis-token")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-		c.Header("Access-Control-Allow-Credentials", "true")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+		if cs.conf.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+	}
 
-		//放行所有OPTIONS方法
-		if method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
+	if !preflight {
+		if cs.exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", cs.exposeHeaders)
 		}
-		// 处理请求
 		c.Next()
+		return
+	}
+
+	// 预检请求直接返回，不进入后续处理
+	c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+	c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+	c.Header("Access-Control-Allow-Methods", cs.allowMethods)
+	if cs.allowHeaders != "" {
+		c.Header("Access-Control-Allow-Headers", cs.allowHeaders)
+	} else if reqHeaders := c.GetHeader("Access-Control-Request-Headers"); reqHeaders != "" {
+		c.Header("Access-Control-Allow-Headers", reqHeaders)
+	}
+	if cs.maxAge != "" {
+		c.Header("Access-Control-Max-Age", cs.maxAge)
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+func (cs *cors) isOriginAllowed(origin string) bool {
+	if cs.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range cs.origins {
+		if matchOrigin(pattern, origin) {
+			return true
+		}
+	}
+	for _, re := range cs.originRegex {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin 支持精确匹配与子域通配，如 "https://*.example.com"、"*.example.com"（不限协议）
+func matchOrigin(pattern, origin string) bool {
+	target := origin
+	if !strings.Contains(pattern, "://") {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		target = u.Host
+	}
+	if pattern == target {
+		return true
+	}
+
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return false
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	if len(target) <= len(prefix)+len(suffix) || !strings.HasPrefix(target, prefix) || !strings.HasSuffix(target, suffix) {
+		return false
 	}
+	// 通配部分只能是子域名，不能跨越协议或路径
+	sub := target[len(prefix) : len(target)-len(suffix)]
+	return !strings.ContainsAny(sub, "/:")
 }
@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+func newCorsRouter(conf *config.CorsConf) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CorsWithConfig(conf))
+	r.POST("/order", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return r
+}
+
+func TestCorsOrigins(t *testing.T) {
+	r := newCorsRouter(&config.CorsConf{
+		AllowOrigins:     []string{"https://app.example.com", "*.example.org"},
+		AllowOriginRegex: []string{`^https://pr-\d+\.preview\.dev$`},
+		AllowMethods:     []string{"GET", "POST"},
+		ExposeHeaders:    []string{"X-Trace-Id"},
+		AllowCredentials: true,
+	})
+
+	cases := []struct {
+		origin string
+		want   string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"http://a.b.example.org", "http://a.b.example.org"},
+		{"https://pr-12.preview.dev", "https://pr-12.preview.dev"},
+		{"https://evil.com", ""},
+		{"https://example.org", ""},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		req.Header.Set("Origin", cs.origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != cs.want {
+			t.Errorf("origin %s: allow-origin=%q want %q", cs.origin, got, cs.want)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("origin %s: missing Vary header", cs.origin)
+		}
+		if cs.want != "" && w.Header().Get("Access-Control-Expose-Headers") != "X-Trace-Id" {
+			t.Errorf("origin %s: missing expose headers", cs.origin)
+		}
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	r := newCorsRouter(&config.CorsConf{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"GET", "POST"},
+		MaxAge:       600,
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/order", nil)
+	req.Header.Set("Origin", "https://any.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("allow-origin should be * without credentials, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w.Header().Get("Access-Control-Allow-Headers") != "X-Custom" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("unexpected preflight headers: %v", w.Header())
+	}
+}
+
+func TestCorsRegexAnchoredAndWildcardWithoutCredentials(t *testing.T) {
+	r := newCorsRouter(&config.CorsConf{
+		AllowOriginRegex: []string{`https://[a-z]+\.example\.com`},
+		AllowMethods:     []string{"POST"},
+		AllowCredentials: true,
+	})
+	for origin, want := range map[string]string{
+		"https://app.example.com":                   "https://app.example.com",
+		"https://evil-example.com.attacker.net":     "",
+		"https://app.example.com.attacker.net":      "",
+		"http://attacker.net/https://a.example.com": "",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("origin %s: allow-origin=%q want %q", origin, got, want)
+		}
+	}
+
+	// 直接传入 "*" + 凭证时不回显来源，也不允许凭证
+	r = newCorsRouter(&config.CorsConf{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	req := httptest.NewRequest(http.MethodPost, "/order", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("wildcard origin must not allow credentials: %v", w.Header())
+	}
+}
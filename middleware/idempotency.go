@@ -0,0 +1,221 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/response"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	idempotencyPrefix = "idempotency:"
+	// 处理中标记的前缀，后面跟随持有者 token
+	idempotencyInflight = "inflight:"
+	// IdempotentReplayedHeader 回放已保存响应时附带的响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// 仍持有处理中标记时才写入完成结果，避免覆盖已过期后被他人重新占用的 key
+var idempotencyCompleteScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return false
+`)
+
+var idempotencyReleaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type idempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Idempotency 按 idempotency 配置对请求做幂等处理，需挂在认证中间件之后以便按用户隔离
+func Idempotency() gin.HandlerFunc {
+	conf, err := config.GetParser().GetIdempotencyConf()
+	if err != nil {
+		panic(errors.New("idempotency config not found"))
+	}
+	pool, err := config.GetRedisPool(conf.RedisIns)
+	if err != nil {
+		panic(fmt.Errorf("idempotency redis init failed: %w", err))
+	}
+	return IdempotencyWith(pool, conf)
+}
+
+// IdempotencyWith 使用指定的 redis 连接池。
+// 首个请求处理期间重复请求返回 409，处理完成后重复请求直接回放保存的状态码与响应体；
+// 5xx 或 panic 不保存结果，客户端可以重试。
+func IdempotencyWith(pool *redis.Pool, conf *config.IdempotencyConf) gin.HandlerFunc {
+	ttl := time.Duration(conf.TTL) * time.Second
+	lockTTL := time.Duration(conf.LockTTL) * time.Second
+
+	return func(c *gin.Context) {
+		idemKey := strings.TrimSpace(c.GetHeader(conf.Header))
+		if idemKey == "" {
+			if conf.Required {
+				response.FailWithStatus(http.StatusBadRequest, response.BadRequest, nil, c)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		key := idempotencyKey(c, idemKey)
+		token, err := newIdempotencyToken()
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		acquired, stored, err := acquireIdempotency(c.Request.Context(), pool, key, token, lockTTL)
+		if err != nil {
+			// redis 不可用时放行，不因幂等组件故障阻断业务
+			log.Ctx(c).Error("idempotency acquire failed", err)
+			c.Next()
+			return
+		}
+		if !acquired {
+			if stored == nil {
+				response.FailWithStatus(http.StatusConflict, response.Conflict, nil, c)
+				return
+			}
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.Status, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		writer := &idempotentWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		// 释放与保存在 handler 之后执行，客户端断开后仍需写入，只保留链路信息不随请求取消
+		saveCtx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if _, err := doScript(saveCtx, pool, idempotencyReleaseScript, key, token); err != nil {
+				log.Ctx(c).Error("idempotency release failed", err)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		data, err := json.Marshal(idempotentResponse{
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		})
+		if err != nil {
+			return
+		}
+		if _, err := doScript(saveCtx, pool, idempotencyCompleteScript, key, token, data, ttl.Milliseconds()); err != nil {
+			log.Ctx(c).Error("idempotency save failed", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// idempotencyKey key 按用户、方法、路由隔离，未认证时按客户端 IP 隔离
+func idempotencyKey(c *gin.Context, idemKey string) string {
+	scope := c.GetString(ContextUserIdKey)
+	if scope == "" {
+		scope = "ip:" + c.ClientIP()
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return idempotencyPrefix + scope + ":" + c.Request.Method + ":" + route + ":" + idemKey
+}
+
+// acquireIdempotency 占用 key；未占用成功时返回已保存的响应，处理中则返回 nil
+func acquireIdempotency(ctx context.Context, pool *redis.Pool, key, token string, lockTTL time.Duration) (bool, *idempotentResponse, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer conn.Close()
+
+	_, err = redis.String(redis.DoContext(conn, ctx, "SET", key, idempotencyInflight+token, "NX", "PX", lockTTL.Milliseconds()))
+	if err == nil {
+		return true, nil, nil
+	}
+	if !errors.Is(err, redis.ErrNil) {
+		return false, nil, err
+	}
+
+	value, err := redis.Bytes(redis.DoContext(conn, ctx, "GET", key))
+	if errors.Is(err, redis.ErrNil) {
+		// 首个请求恰好失败释放，本次按处理中返回，由客户端重试
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	if bytes.HasPrefix(value, []byte(idempotencyInflight)) {
+		return false, nil, nil
+	}
+	stored := &idempotentResponse{}
+	if err := json.Unmarshal(value, stored); err != nil {
+		return false, nil, err
+	}
+	return false, stored, nil
+}
+
+func doScript(ctx context.Context, pool *redis.Pool, script *redis.Script, key, token string, args ...interface{}) (interface{}, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return script.DoContext(ctx, conn, append([]interface{}{key, idempotencyInflight + token}, args...)...)
+}
+
+func newIdempotencyToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// idempotentWriter 捕获响应体以便保存
+type idempotentWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotentWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotentWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	defer pool.Close()
+
+	var calls int32
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(ContextUserIdKey, c.GetHeader("X-User"))
+	})
+	r.Use(IdempotencyWith(pool, &config.IdempotencyConf{Header: "Idempotency-Key", TTL: 60, LockTTL: 10}))
+	r.POST("/pay", func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		if c.Query("wait") != "" {
+			<-release
+		}
+		if c.Query("fail") != "" {
+			c.String(http.StatusInternalServerError, "fail")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	do := func(user, key, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pay"+query, nil)
+		req.Header.Set("X-User", user)
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 首个请求处理中，重复请求返回 409
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- do("u1", "k1", "?wait=1") }()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := do("u1", "k1", "?wait=1"); w.Code != http.StatusConflict {
+		t.Fatalf("in-flight duplicate: %d", w.Code)
+	}
+	close(release)
+	first := <-done
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: %d", first.Code)
+	}
+
+	// 完成后重复请求回放首个响应
+	w := do("u1", "k1", "")
+	if w.Code != http.StatusCreated || w.Body.String() != first.Body.String() || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("replay: %d %s", w.Code, w.Body.String())
+	}
+
+	// 同一个 key 不同用户互不影响
+	if w := do("u2", "k1", ""); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("other user: %d", w.Code)
+	}
+
+	// 5xx 不保存，可以重试
+	if w := do("u1", "k2", "?fail=1"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("failed request: %d", w.Code)
+	}
+	if w := do("u1", "k2", ""); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("retry after failure: %d", w.Code)
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Fatalf("handler calls %d", n)
+	}
+}
@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/response"
+	"net/http"
+	"time"
+)
+
+// getRequestLimitConf 读取 request_limit 配置，未配置时请求体 10MB、超时 30 秒
+func getRequestLimitConf() *config.RequestLimitConf {
+	conf, err := config.GetParser().GetRequestLimitConf()
+	if err != nil {
+		return &config.RequestLimitConf{MaxBodySize: 10 << 20, Timeout: 30000}
+	}
+	return conf
+}
+
+// BodyLimitMiddleware 限制请求体大小，需放在 RequestLogger 之前，避免超大请求体被读入内存
+func BodyLimitMiddleware() gin.HandlerFunc {
+	return BodyLimitWithConfig(getRequestLimitConf())
+}
+
+// BodyLimitWithConfig 使用指定配置限制请求体大小
+func BodyLimitWithConfig(conf *config.RequestLimitConf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := conf.MaxBodySize
+		if route, ok := matchRequestLimitRoute(conf, c); ok && route.MaxBodySize != 0 {
+			limit = route.MaxBodySize
+		}
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			response.FailWithStatus(http.StatusRequestEntityTooLarge, response.PayloadTooLarge, nil, c)
+			return
+		}
+
+		// 分块传输等未声明长度的请求，读取超过上限时返回错误
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// TimeoutMiddleware 为请求上下文设置截止时间，只负责把截止时间传递给下游调用
+func TimeoutMiddleware() gin.HandlerFunc {
+	return TimeoutWithConfig(getRequestLimitConf())
+}
+
+// TimeoutWithConfig 使用指定配置为 c.Request.Context() 设置截止时间。
+// 不会中断正在执行的 handler，也不会替换 handler 已写出的响应：下游调用因超时返回错误后，
+// handler 写出的响应（如 500）原样返回；只有超时后 handler 没有写任何响应时才补充 504。
+func TimeoutWithConfig(conf *config.RequestLimitConf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := conf.Timeout
+		if route, ok := matchRequestLimitRoute(conf, c); ok && route.Timeout != 0 {
+			timeout = route.Timeout
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(timeout)*time.Millisecond)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			response.FailWithStatus(http.StatusGatewayTimeout, response.Timeout, nil, c)
+		}
+	}
+}
+
+func matchRequestLimitRoute(conf *config.RequestLimitConf, c *gin.Context) (config.RequestLimitRoute, bool) {
+	for _, route := range conf.Routes {
+		if len(route.Methods) > 0 && !containsFold(route.Methods, c.Request.Method) {
+			continue
+		}
+		if matchRoute(route.Route, c.FullPath(), c.Request.URL.Path) {
+			return route, true
+		}
+	}
+	return config.RequestLimitRoute{}, false
+}
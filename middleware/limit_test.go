@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := &config.RequestLimitConf{
+		MaxBodySize: 8,
+		Routes:      []config.RequestLimitRoute{{Route: "/upload", MaxBodySize: 1024}},
+	}
+	r := gin.New()
+	r.Use(BodyLimitWithConfig(conf))
+	handler := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.String(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	}
+	r.POST("/order", handler)
+	r.POST("/upload", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader("0123456789")))
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), `"code":413`) {
+		t.Fatalf("declared length over limit: %d %s", w.Code, w.Body.String())
+	}
+
+	// 未声明长度时由 MaxBytesReader 在读取时拦截
+	req := httptest.NewRequest(http.MethodPost, "/order", io.NopCloser(strings.NewReader("0123456789")))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("chunked body over limit: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("route limit should override default: %d", w.Code)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TimeoutWithConfig(&config.RequestLimitConf{Timeout: 20}))
+	r.GET("/slow", func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(time.Second):
+			c.String(http.StatusOK, "ok")
+		}
+	})
+	r.GET("/fast", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/failed", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.String(http.StatusInternalServerError, "query canceled")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), `"code":504`) {
+		t.Fatalf("slow request: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("fast request: %d", w.Code)
+	}
+
+	// 只传递截止时间，handler 已写出的响应不会被替换为 504
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/failed", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "query canceled" {
+		t.Fatalf("handler response should be kept: %d %s", w.Code, w.Body.String())
+	}
+}
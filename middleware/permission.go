@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/auth"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/rbac"
+	"github.com/hyzx-go/common-b2c/response"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	defaultEnforcer     *rbac.Enforcer
+	defaultEnforcerOnce sync.Once
+)
+
+// newEnforcerFromConfig 按 rbac 配置从配置文件或数据库加载策略
+func newEnforcerFromConfig() (*rbac.Enforcer, error) {
+	conf, err := config.GetParser().GetRbacConf()
+	if err != nil {
+		return nil, errors.New("rbac config not found")
+	}
+
+	opts := []rbac.Option{rbac.SetCacheTTL(time.Duration(conf.CacheTTL) * time.Second)}
+	if conf.Source != config.RbacSourceDB {
+		return rbac.New(conf.Policies, opts...), nil
+	}
+
+	db, err := config.GetMysqlDB(conf.MysqlIns)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, rbac.SetReload(time.Duration(conf.Reload)*time.Second))
+	return rbac.NewWithLoader(context.Background(), rbac.NewGormLoader(db, conf.Table), opts...)
+}
+
+// GetEnforcer 全局共享的权限判定，可用于注册属性条件
+func GetEnforcer() *rbac.Enforcer {
+	defaultEnforcerOnce.Do(func() {
+		enforcer, err := newEnforcerFromConfig()
+		if err != nil {
+			panic(fmt.Errorf("rbac enforcer init failed: %w", err))
+		}
+		defaultEnforcer = enforcer
+	})
+	return defaultEnforcer
+}
+
+// RequirePermission 要求拥有全部权限，需挂在 JWTAuth 之后
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return RequirePermissionWithEnforcer(GetEnforcer(), permissions...)
+}
+
+// RequirePermissionWithEnforcer 使用指定的 Enforcer，未认证返回 401，无权限返回 403
+func RequirePermissionWithEnforcer(enforcer *rbac.Enforcer, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := auth.FromContext(c)
+		if !ok || claims == nil {
+			response.FailWithStatus(http.StatusUnauthorized, response.Unauthorized, nil, c)
+			return
+		}
+
+		sub := rbac.Subject{
+			UserId:   claims.UserId,
+			TenantId: claims.TenantId,
+			Roles:    claims.Roles,
+			Attrs:    claims.Extra,
+		}
+		for _, permission := range permissions {
+			if !enforcer.Allow(c, sub, permission) {
+				response.FailWithStatus(http.StatusForbidden, response.PermissionError, nil, c)
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/auth"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/rbac"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enforcer := rbac.New([]config.RbacPolicy{
+		{Role: "editor", Permissions: []string{"order:*"}},
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if roles := c.GetHeader("X-Roles"); roles != "" {
+			c.Set(auth.GinClaimsKey, &auth.Claims{UserId: "u1", Roles: []string{roles}})
+		}
+	})
+	r.POST("/order", RequirePermissionWithEnforcer(enforcer, "order:write"), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	cases := []struct {
+		roles string
+		code  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"viewer", http.StatusForbidden},
+		{"editor", http.StatusOK},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		req.Header.Set("X-Roles", cs.roles)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != cs.code {
+			t.Errorf("roles %q: status %d want %d", cs.roles, w.Code, cs.code)
+		}
+	}
+}
+
+func TestJWTAuthThenRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := []byte("secret")
+	verifier, err := auth.NewVerifier(auth.Options{Algorithm: auth.HS256, Secret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer := rbac.New([]config.RbacPolicy{
+		{Role: "editor", Permissions: []string{"order:*"}},
+	})
+
+	r := gin.New()
+	r.POST("/order",
+		JWTAuthWithVerifier(verifier, &config.AuthConf{Header: "Authorization", Scheme: "Bearer"}, AuthRequired),
+		RequirePermissionWithEnforcer(enforcer, "order:write"),
+		func(c *gin.Context) { c.String(http.StatusOK, "ok") },
+	)
+
+	sign := func(roles ...string) string {
+		token, err := auth.SignHS256(map[string]interface{}{"uid": "u1", "roles": roles, "exp": time.Now().Unix() + 60}, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	cases := []struct {
+		token string
+		code  int
+	}{
+		{"", http.StatusUnauthorized},
+		{sign("viewer"), http.StatusForbidden},
+		{sign("editor"), http.StatusOK},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		if cs.token != "" {
+			req.Header.Set("Authorization", cs.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != cs.code {
+			t.Errorf("token %q: status %d want %d", cs.token, w.Code, cs.code)
+		}
+	}
+}
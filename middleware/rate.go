@@ -1,80 +1,250 @@
 package middlewares
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/hyzx-go/common-b2c/utils"
-	"golang.org/x/time/rate"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/response"
+	"math"
+	"net"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
-// 定义全局的限流器存储
-type ipLimiter struct {
-	limiter  *rate.Limiter // 具体的限流器
-	lastSeen time.Time     // 最后一次访问时间
-}
+const (
+	// ContextUserIdKey 认证中间件写入 gin.Context 的用户 id，限流按用户计数时使用
+	ContextUserIdKey = "user_id"
+	// ContextRateTierKey 认证中间件写入 gin.Context 的限流等级
+	ContextRateTierKey = "rate_tier"
+)
 
 var (
-	limiterStore = make(map[string]*ipLimiter) // 存储每个 IP 的限流器
-	storeMutex   sync.Mutex                    // 保护共享数据的互斥锁
-	rateLimit    = rate.Limit(10)              // 每秒允许的请求数
-	burstLimit   = 15                          // 令牌桶的容量
-	expireAfter  = 5 * time.Minute             // 限流器过期时间
+	defaultLimiter     Limiter
+	defaultLimiterOnce sync.Once
 )
 
-// 获取或创建 IP 的限流器
-func getLimiter(ip string) *rate.Limiter {
-	storeMutex.Lock()
-	defer storeMutex.Unlock()
+// newLimiterFromConfig 按 rate_limit 配置选择限流后端，未配置时使用进程内限流
+func newLimiterFromConfig() Limiter {
+	conf, err := config.GetParser().GetRateLimitConf()
+	if err != nil || conf.Backend != config.RateLimitBackendRedis {
+		return NewMemoryLimiter()
+	}
 
-	// 如果限流器存在并未过期，则返回
-	if limiter, exists := limiterStore[ip]; exists {
-		limiter.lastSeen = time.Now()
-		return limiter.limiter
+	pool, err := config.GetRedisPool(conf.RedisIns)
+	if err != nil {
+		panic(fmt.Errorf("rate limit redis backend init failed: %w", err))
 	}
+	return NewRedisLimiter(pool)
+}
 
-	// 创建新的限流器
-	limiter := rate.NewLimiter(rateLimit, burstLimit)
-	limiterStore[ip] = &ipLimiter{limiter: limiter, lastSeen: time.Now()}
+// getDefaultLimiter 全局共享的限流后端
+func getDefaultLimiter() Limiter {
+	defaultLimiterOnce.Do(func() {
+		defaultLimiter = newLimiterFromConfig()
+	})
+	return defaultLimiter
+}
 
-	return limiter
+// getRateLimitConf 读取 rate_limit 配置，未配置时每个 IP 每秒 10 次，容量 15
+func getRateLimitConf() *config.RateLimitConf {
+	conf, err := config.GetParser().GetRateLimitConf()
+	if err != nil {
+		return &config.RateLimitConf{Backend: config.RateLimitBackendMemory, Rate: 10, Burst: 15}
+	}
+	return conf
 }
 
-// 清理过期的限流器
-func cleanupLimiters() {
-	storeMutex.Lock()
-	defer storeMutex.Unlock()
+// 中间件实现，按路由匹配第一条策略，都不匹配时使用默认限额。
+// 该中间件挂在认证之前，key_by 为 user 的策略不参与匹配，需在认证中间件之后通过 RateLimitByPolicy 挂载
+func RateLimitMiddleware() gin.HandlerFunc {
+	return RateLimitWithLimiter(getDefaultLimiter(), getRateLimitConf())
+}
 
-	for ip, limiter := range limiterStore {
-		if time.Since(limiter.lastSeen) > expireAfter {
-			delete(limiterStore, ip)
+// RateLimitByPolicy 只应用指定名称的策略，必须挂在认证中间件之后，
+// 认证中间件需写入 ContextUserIdKey 与 ContextRateTierKey，未登录的请求按 IP 计数
+func RateLimitByPolicy(name string) gin.HandlerFunc {
+	conf := getRateLimitConf()
+	for _, policy := range conf.Policies {
+		if policy.Name == name {
+			rl := newRateLimiter(getDefaultLimiter(), conf)
+			return func(c *gin.Context) {
+				rl.handle(c, policy)
+			}
 		}
 	}
+	panic(fmt.Errorf("rate limit policy not exist:%s", name))
 }
 
-// 中间件实现
-func RateLimitMiddleware() gin.HandlerFunc {
-	clearFn := func() {
-		for {
-			time.Sleep(time.Minute) // 定时清理
-			cleanupLimiters()
+// RateLimitWithLimiter 使用指定的限流后端和策略，按路由匹配时跳过 key_by 为 user 的策略
+func RateLimitWithLimiter(limiter Limiter, conf *config.RateLimitConf) gin.HandlerFunc {
+	rl := newRateLimiter(limiter, conf)
+	for _, policy := range conf.Policies {
+		if policy.KeyBy == config.RateLimitKeyByUser {
+			log.Ctx(context.Background()).Warn("rate limit policy keyed by user is skipped by route matching, mount RateLimitByPolicy after auth", policy.Name)
 		}
 	}
-	utils.GoSafeWithRetry(clearFn, 2)
-
 	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		limiter := getLimiter(clientIP)
-
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Too many requests",
-			})
-			c.Abort()
-			return
+		rl.handle(c, rl.match(c))
+	}
+}
+
+type rateLimiter struct {
+	limiter   Limiter
+	conf      *config.RateLimitConf
+	allowNets []*net.IPNet
+	allowKeys map[string]struct{}
+}
+
+func newRateLimiter(limiter Limiter, conf *config.RateLimitConf) *rateLimiter {
+	rl := &rateLimiter{limiter: limiter, conf: conf, allowKeys: make(map[string]struct{})}
+	for _, item := range conf.Allowlist {
+		if _, ipNet, err := net.ParseCIDR(item); err == nil {
+			rl.allowNets = append(rl.allowNets, ipNet)
+			continue
 		}
+		rl.allowKeys[item] = struct{}{}
+	}
+	return rl
+}
+
+func (rl *rateLimiter) handle(c *gin.Context, policy config.RateLimitPolicy) {
+	key := rl.resolveKey(c, policy.KeyBy)
+	if rl.isAllowed(c.ClientIP(), key) {
+		c.Next()
+		return
+	}
 
+	limit := Limit{Rate: policy.Rate, Burst: policy.Burst}
+	if tier := c.GetString(ContextRateTierKey); tier != "" {
+		if quota, ok := policy.Tiers[tier]; ok {
+			limit = Limit{Rate: quota.Rate, Burst: quota.Burst}
+		}
+	}
+
+	result, err := rl.allow(c, policy, key, limit)
+	if err != nil {
+		// 限流后端异常时放行，避免影响正常业务
+		log.Ctx(c.Request.Context()).Warn("rate limit backend error", err)
 		c.Next()
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		response.FailWithStatus(http.StatusTooManyRequests, response.TooManyRequests, nil, c)
+		return
+	}
+
+	c.Next()
+}
+
+// allow 按请求头计数时 key 由客户端决定，更换请求头即可拿到新的令牌桶，
+// 因此同一 IP 还需通过默认限额的检查
+func (rl *rateLimiter) allow(c *gin.Context, policy config.RateLimitPolicy, key string, limit Limit) (LimitResult, error) {
+	ctx := c.Request.Context()
+	if strings.HasPrefix(key, "header:") {
+		guard, err := rl.limiter.Allow(ctx, policy.Name+":ip:"+c.ClientIP(), Limit{Rate: rl.conf.Rate, Burst: rl.conf.Burst})
+		if err != nil || !guard.Allowed {
+			return guard, err
+		}
+	}
+	return rl.limiter.Allow(ctx, policy.Name+":"+key, limit)
+}
+
+// match 返回第一条匹配当前请求的策略，key_by 为 user 的策略只能通过 RateLimitByPolicy 使用
+func (rl *rateLimiter) match(c *gin.Context) config.RateLimitPolicy {
+	for _, policy := range rl.conf.Policies {
+		if policy.KeyBy == config.RateLimitKeyByUser {
+			continue
+		}
+		if len(policy.Methods) > 0 && !containsFold(policy.Methods, c.Request.Method) {
+			continue
+		}
+		if matchRoute(policy.Route, c.FullPath(), c.Request.URL.Path) {
+			return policy
+		}
+	}
+	return config.RateLimitPolicy{
+		Name:  "default",
+		KeyBy: config.RateLimitKeyByIP,
+		Rate:  rl.conf.Rate,
+		Burst: rl.conf.Burst,
+	}
+}
+
+// resolveKey 计算限流 key，取不到用户或请求头时退化为按 IP
+func (rl *rateLimiter) resolveKey(c *gin.Context, keyBy string) string {
+	switch {
+	case keyBy == config.RateLimitKeyByUser:
+		if userId := c.GetString(ContextUserIdKey); userId != "" {
+			return "user:" + userId
+		}
+	case strings.HasPrefix(keyBy, config.RateLimitKeyByHeader):
+		header := strings.TrimPrefix(keyBy, config.RateLimitKeyByHeader)
+		if value := c.GetHeader(header); value != "" {
+			return "header:" + value
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// isAllowed 白名单支持 IP、CIDR 以及限流 key（如 user:1001）
+func (rl *rateLimiter) isAllowed(clientIP, key string) bool {
+	if _, ok := rl.allowKeys[clientIP]; ok {
+		return true
+	}
+	if _, ok := rl.allowKeys[key]; ok {
+		return true
+	}
+	if len(rl.allowNets) > 0 {
+		if ip := net.ParseIP(clientIP); ip != nil {
+			for _, ipNet := range rl.allowNets {
+				if ipNet.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchRoute 支持 gin 路由模板精确匹配、"/api/*" 前缀匹配以及 path.Match 通配
+func matchRoute(pattern, fullPath, urlPath string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") && !strings.ContainsAny(strings.TrimSuffix(pattern, "*"), "*?[") {
+		return strings.HasPrefix(urlPath, strings.TrimSuffix(pattern, "*"))
+	}
+	if pattern == fullPath || pattern == urlPath {
+		return true
+	}
+	matched, _ := path.Match(pattern, urlPath)
+	return matched
+}
+
+func containsFold(items []string, s string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
 	}
+	return int(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/utils"
+)
+
+// Limit 限流规则：每秒 Rate 个请求，令牌桶容量 Burst；Rate 或 Burst 不大于 0 时不限流
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// LimitResult 单次限流判定结果
+type LimitResult struct {
+	Allowed    bool
+	Limit      int           // 令牌桶容量
+	Remaining  int           // 剩余可用请求数
+	RetryAfter time.Duration // 被拒绝时，距离下次可用的时间
+	ResetAfter time.Duration // 令牌桶恢复满额所需时间
+}
+
+// Limiter 限流后端
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (LimitResult, error)
+}
+
+// 定义全局的限流器存储
+type ipLimiter struct {
+	limiter  *rate.Limiter // 具体的限流器
+	lastSeen time.Time     // 最后一次访问时间
+}
+
+// memoryLimiter 进程内限流，多副本部署时每个副本单独计数
+type memoryLimiter struct {
+	store       map[string]*ipLimiter // 存储每个 key 的限流器
+	mu          sync.Mutex            // 保护共享数据的互斥锁
+	expireAfter time.Duration         // 限流器过期时间
+	cleaning    bool                  // 清理 goroutine 是否在运行
+}
+
+// NewMemoryLimiter 创建进程内限流后端。
+// 清理 goroutine 在首次写入时启动，限流器全部过期后退出，不再使用的后端不会留下常驻 goroutine
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		store:       make(map[string]*ipLimiter),
+		expireAfter: 5 * time.Minute,
+	}
+}
+
+func (m *memoryLimiter) Allow(_ context.Context, key string, limit Limit) (LimitResult, error) {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return LimitResult{Allowed: true}, nil
+	}
+	limiter := m.get(key, limit)
+	now := time.Now()
+
+	result := LimitResult{Limit: limit.Burst}
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		result.RetryAfter = delay
+	} else {
+		result.Allowed = true
+	}
+
+	tokens := limiter.TokensAt(now)
+	result.Remaining = int(math.Max(0, math.Floor(tokens)))
+	if limit.Rate > 0 {
+		missing := float64(limit.Burst) - tokens
+		result.ResetAfter = time.Duration(missing / limit.Rate * float64(time.Second))
+	}
+	return result, nil
+}
+
+// 获取或创建 key 的限流器
+func (m *memoryLimiter) get(key string, limit Limit) *rate.Limiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 规则变化时 key 不同，会重新创建限流器
+	storeKey := fmt.Sprintf("%s|%v|%d", key, limit.Rate, limit.Burst)
+	if limiter, exists := m.store[storeKey]; exists {
+		limiter.lastSeen = time.Now()
+		return limiter.limiter
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+	m.store[storeKey] = &ipLimiter{limiter: limiter, lastSeen: time.Now()}
+	if !m.cleaning {
+		m.cleaning = true
+		utils.GoSafeWithRetry(m.janitor, 2)
+	}
+	return limiter
+}
+
+// janitor 定时清理，store 为空时退出，下次写入时重新启动
+func (m *memoryLimiter) janitor() {
+	for {
+		time.Sleep(time.Minute)
+		if m.cleanup() == 0 {
+			return
+		}
+	}
+}
+
+// 清理过期的限流器，返回剩余数量；为 0 时同时标记清理 goroutine 已退出
+func (m *memoryLimiter) cleanup() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, limiter := range m.store {
+		if time.Since(limiter.lastSeen) > m.expireAfter {
+			delete(m.store, key)
+		}
+	}
+	if len(m.store) == 0 {
+		m.cleaning = false
+	}
+	return len(m.store)
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func testLimiter(t *testing.T, limiter Limiter) {
+	ctx := context.Background()
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		res, err := limiter.Allow(ctx, "127.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d should be allowed", i)
+		}
+		if res.Remaining != 2-i {
+			t.Fatalf("request %d remaining=%d", i, res.Remaining)
+		}
+	}
+
+	res, err := limiter.Allow(ctx, "127.0.0.1", limit)
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	if res.Allowed || res.RetryAfter <= 0 {
+		t.Fatalf("request over burst should be rejected: %+v", res)
+	}
+
+	if res, _ := limiter.Allow(ctx, "127.0.0.2", limit); !res.Allowed {
+		t.Fatal("other key should not be limited")
+	}
+
+	// 未设置限额时不限流，两种后端行为一致
+	for i := 0; i < 3; i++ {
+		if res, err := limiter.Allow(ctx, "127.0.0.3", Limit{}); err != nil || !res.Allowed {
+			t.Fatalf("zero limit should not reject: %+v %v", res, err)
+		}
+	}
+}
+
+func TestMemoryLimiterJanitorExits(t *testing.T) {
+	m := NewMemoryLimiter().(*memoryLimiter)
+	m.expireAfter = 0
+	if _, err := m.Allow(context.Background(), "127.0.0.1", Limit{Rate: 1, Burst: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !m.cleaning {
+		t.Fatal("janitor should start on first write")
+	}
+	if n := m.cleanup(); n != 0 || m.cleaning {
+		t.Fatalf("janitor should stop when store is empty, remaining=%d", n)
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	testLimiter(t, NewMemoryLimiter())
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	defer pool.Close()
+
+	testLimiter(t, NewRedisLimiter(pool))
+}
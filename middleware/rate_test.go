@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+func newRateLimitRouter(conf *config.RateLimitConf, middlewares ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares...)
+	r.Use(RateLimitWithLimiter(NewMemoryLimiter(), conf))
+	r.GET("/api/order/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/api/user", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return r
+}
+
+func doRequest(r http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	r := newRateLimitRouter(&config.RateLimitConf{
+		Rate:  100,
+		Burst: 100,
+		Policies: []config.RateLimitPolicy{
+			{Name: "order", Route: "/api/order/:id", KeyBy: "header:X-Api-Key", Rate: 1, Burst: 1},
+		},
+	})
+
+	w := doRequest(r, "/api/order/1", map[string]string{"X-Api-Key": "a"})
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("first request: %d %v", w.Code, w.Header())
+	}
+
+	w = doRequest(r, "/api/order/2", map[string]string{"X-Api-Key": "a"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request should be limited, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("missing rate limit headers: %v", w.Header())
+	}
+
+	if w := doRequest(r, "/api/order/3", map[string]string{"X-Api-Key": "b"}); w.Code != http.StatusOK {
+		t.Fatalf("other api key should not be limited, got %d", w.Code)
+	}
+	if w := doRequest(r, "/api/user", nil); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Fatalf("default policy expected, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestRateLimitHeaderKeyGuardedByIP(t *testing.T) {
+	r := newRateLimitRouter(&config.RateLimitConf{
+		Rate:  2,
+		Burst: 2,
+		Policies: []config.RateLimitPolicy{
+			{Name: "order", Route: "/api/order/:id", KeyBy: "header:X-Api-Key", Rate: 10, Burst: 10},
+		},
+	})
+
+	// 每次更换请求头拿到新的令牌桶，但同一 IP 仍受默认限额约束
+	for _, key := range []string{"a", "b"} {
+		if w := doRequest(r, "/api/order/1", map[string]string{"X-Api-Key": key}); w.Code != http.StatusOK {
+			t.Fatalf("api key %s: got %d", key, w.Code)
+		}
+	}
+	if w := doRequest(r, "/api/order/1", map[string]string{"X-Api-Key": "c"}); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("rotated header should be limited by ip, got %d", w.Code)
+	}
+}
+
+func TestRateLimitUserPolicySkippedByRouteMatch(t *testing.T) {
+	r := newRateLimitRouter(&config.RateLimitConf{
+		Rate:  100,
+		Burst: 100,
+		Policies: []config.RateLimitPolicy{
+			{Name: "user", Route: "/api/*", KeyBy: "user", Rate: 1, Burst: 1},
+		},
+	})
+
+	if w := doRequest(r, "/api/user", nil); w.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Fatalf("user keyed policy should only apply after auth, got %v", w.Header())
+	}
+}
+
+func TestRateLimitTierAndAllowlist(t *testing.T) {
+	// 限流等级只取认证中间件写入的值，客户端请求头无效
+	auth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "pro-token" {
+			c.Set(ContextRateTierKey, "pro")
+		}
+	}
+	r := newRateLimitRouter(&config.RateLimitConf{
+		Allowlist: []string{"192.168.0.0/16"},
+		Policies: []config.RateLimitPolicy{
+			{
+				Name: "api", Route: "/api/*", KeyBy: "ip", Rate: 1, Burst: 1,
+				Tiers: map[string]config.RateLimitQuota{"pro": {Rate: 10, Burst: 5}},
+			},
+		},
+	}, auth)
+
+	if w := doRequest(r, "/api/user", map[string]string{"X-Plan": "pro"}); w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("tier from request header must be ignored, got %v", w.Header())
+	}
+	if w := doRequest(r, "/api/user", map[string]string{"Authorization": "pro-token"}); w.Header().Get("X-RateLimit-Limit") != "5" {
+		t.Fatalf("pro tier quota expected, got %v", w.Header())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+	req.RemoteAddr = "192.168.1.10:1234"
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("allowlisted ip should bypass limit, got %d", w.Code)
+		}
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	cases := []struct {
+		pattern, fullPath, urlPath string
+		want                       bool
+	}{
+		{"", "/a", "/a", true},
+		{"/api/*", "/api/order/:id", "/api/order/1", true},
+		{"/api/order/:id", "/api/order/:id", "/api/order/1", true},
+		{"/api/*/detail", "", "/api/order/detail", true},
+		{"/api/user", "/api/order/:id", "/api/order/1", false},
+	}
+	for _, cs := range cases {
+		if got := matchRoute(cs.pattern, cs.fullPath, cs.urlPath); got != cs.want {
+			t.Errorf("matchRoute(%q, %q, %q) = %v", cs.pattern, cs.fullPath, cs.urlPath, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"github.com/hyzx-go/common-b2c/utils"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// W3C traceparent: version-traceid-parentid-flags
+var traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// getRequestIdConf 读取 request_id 配置，未配置时读取 X-Request-Id 与 traceparent
+func getRequestIdConf() *config.RequestIdConf {
+	conf, err := config.GetParser().GetRequestIdConf()
+	if err != nil {
+		return &config.RequestIdConf{
+			Headers:        []string{"X-Request-Id", "traceparent"},
+			Pattern:        `^[A-Za-z0-9._:-]{8,128}$`,
+			ResponseHeader: "X-Request-Id",
+		}
+	}
+	return conf
+}
+
+// RequestId 沿用上游传入的请求 id，没有或格式不合法时生成新的
+func RequestId() gin.HandlerFunc {
+	return RequestIdWithConfig(getRequestIdConf())
+}
+
+// RequestIdWithConfig 使用指定配置。
+// id 写入 gin.Context 与 c.Request.Context() 的 trace-id，并通过响应头返回；
+// 已启用链路追踪时使用 span 的 trace id，保持与链路一致。
+func RequestIdWithConfig(conf *config.RequestIdConf) gin.HandlerFunc {
+	pattern := regexp.MustCompile(conf.Pattern)
+
+	return func(c *gin.Context) {
+		requestId := ctxkeys.TraceId(c)
+		if requestId == "" {
+			requestId = inboundRequestId(c.Request, conf.Headers, pattern)
+		}
+		if requestId == "" {
+			requestId = utils.GetTraceId()
+		}
+
+		ctxkeys.SetTraceId(c, requestId)
+		if conf.ResponseHeader != "" {
+			c.Header(conf.ResponseHeader, requestId)
+		}
+		c.Next()
+	}
+}
+
+func inboundRequestId(req *http.Request, headers []string, pattern *regexp.Regexp) string {
+	for _, header := range headers {
+		value := strings.TrimSpace(req.Header.Get(header))
+		if value == "" {
+			continue
+		}
+		if strings.EqualFold(header, "traceparent") {
+			if m := traceparentPattern.FindStringSubmatch(value); m != nil && m[1] != strings.Repeat("0", 32) {
+				return m[1]
+			}
+			continue
+		}
+		if pattern.MatchString(value) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"github.com/hyzx-go/common-b2c/rpc"
+)
+
+func TestRequestId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 下游回显收到的 X-Request-Id
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(rpc.RequestIdHeader)))
+	}))
+	defer downstream.Close()
+	client := rpc.NewHttpClient(http.DefaultClient)
+
+	r := gin.New()
+	r.Use(RequestIdWithConfig(getRequestIdConf()))
+	r.GET("/order", func(c *gin.Context) {
+		if ctxId := ctxkeys.TraceId(c.Request.Context()); ctxId != c.GetString(ctxkeys.GinTraceIdKey) {
+			t.Errorf("request context id %q != gin id %q", ctxId, c.GetString(ctxkeys.GinTraceIdKey))
+		}
+		req := rpc.NewHttpClientBuilder().SetRequestType(rpc.Get).SetUrl(downstream.URL).Build()
+		_, data, err := client.Call(c.Request.Context(), req, time.Second)
+		if err != nil {
+			t.Error(err)
+		}
+		c.String(http.StatusOK, data)
+	})
+
+	cases := []struct {
+		header, value string
+		want          string
+	}{
+		{"X-Request-Id", "req-12345678", "req-12345678"},
+		{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"X-Request-Id", "bad id<script>", ""},
+		{"", "", ""},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/order", nil)
+		if cs.header != "" {
+			req.Header.Set(cs.header, cs.value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get("X-Request-Id")
+		if cs.want != "" && got != cs.want {
+			t.Errorf("%s=%q: request id %q want %q", cs.header, cs.value, got, cs.want)
+		}
+		if cs.want == "" && (len(got) != 32 || got == cs.value) {
+			t.Errorf("%s=%q: expected generated id, got %q", cs.header, cs.value, got)
+		}
+		if w.Body.String() != got {
+			t.Errorf("rpc forwarded %q want %q", w.Body.String(), got)
+		}
+	}
+}
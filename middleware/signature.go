@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/log"
+	"github.com/hyzx-go/common-b2c/response"
+	"github.com/hyzx-go/common-b2c/signature"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ContextSignAppIdKey 验签通过后写入 gin.Context 的调用方 app id
+const ContextSignAppIdKey = "sign_app_id"
+
+// newSignVerifierFromConfig 按 sign 配置创建验签器，nonce 按配置存放在内存或 redis
+func newSignVerifierFromConfig() (*signature.Verifier, error) {
+	conf, err := config.GetParser().GetSignConf()
+	if err != nil {
+		return nil, errors.New("sign config not found")
+	}
+
+	nonces := signature.NewMemoryNonceStore()
+	if conf.NonceBackend == config.NonceBackendRedis {
+		pool, err := config.GetRedisPool(conf.RedisIns)
+		if err != nil {
+			return nil, err
+		}
+		nonces = signature.NewRedisNonceStore(pool)
+	}
+	return signature.NewVerifier(conf.Apps, time.Duration(conf.Expire)*time.Second, nonces), nil
+}
+
+// VerifySignature 按 sign 配置校验请求签名，需挂在 BodyLimitMiddleware 之后
+func VerifySignature() gin.HandlerFunc {
+	verifier, err := newSignVerifierFromConfig()
+	if err != nil {
+		panic(fmt.Errorf("sign verifier init failed: %w", err))
+	}
+	return VerifySignatureWith(verifier)
+}
+
+// VerifySignatureWith 使用指定的验签器，失败返回 401
+func VerifySignatureWith(verifier *signature.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				response.FailWithStatus(http.StatusBadRequest, response.BadRequest, nil, c)
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		appId, err := verifier.Verify(c.Request.Context(), c.Request, body)
+		if err != nil {
+			log.Ctx(c).Warn("signature verify failed", fmt.Sprintf("app_id:%s, path:%s, err:%v", appId, c.Request.URL.Path, err))
+			response.FailWithStatus(http.StatusUnauthorized, response.AuthenticationError, nil, c)
+			return
+		}
+		c.Set(ContextSignAppIdKey, appId)
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/rpc"
+	"github.com/hyzx-go/common-b2c/signature"
+)
+
+func TestVerifySignatureWithRpcSigner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier := signature.NewVerifier(map[string]string{"order-svc": "secret"}, time.Minute, signature.NewMemoryNonceStore())
+
+	r := gin.New()
+	r.Use(VerifySignatureWith(verifier))
+	r.POST("/internal/order", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(ContextSignAppIdKey))
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req := rpc.NewHttpClientBuilder().SetRequestType(rpc.PostJson).SetBaseUrl(server.URL).
+		SetUrl("/internal/order").SetParams(rpc.Params{"b": "2", "a": "1"}).SetData(map[string]int{"id": 1}).Build()
+
+	signed := rpc.NewHttpClient(http.DefaultClient, signature.NewSigner("order-svc", "secret").Sign)
+	code, data, err := signed.Call(context.Background(), req, time.Second)
+	if err != nil || code != http.StatusOK || data != "order-svc" {
+		t.Fatalf("signed call: %d %s %v", code, data, err)
+	}
+
+	unsigned := rpc.NewHttpClient(http.DefaultClient)
+	req.Url = "/internal/order"
+	if code, _, _ := unsigned.Call(context.Background(), req, time.Second); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned call status %d", code)
+	}
+}
@@ -0,0 +1,61 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hyzx-go/common-b2c/config"
+	"gorm.io/gorm"
+)
+
+// Loader 策略来源
+type Loader interface {
+	Load(ctx context.Context) ([]config.RbacPolicy, error)
+}
+
+// StaticLoader 固定策略，通常来自 rbac.policies 配置
+type StaticLoader []config.RbacPolicy
+
+func (s StaticLoader) Load(ctx context.Context) ([]config.RbacPolicy, error) {
+	return s, nil
+}
+
+// PolicyRecord 数据库策略表的一行，一行一个授权
+type PolicyRecord struct {
+	Role       string `gorm:"column:role"`
+	Permission string `gorm:"column:permission"`
+	Inherits   string `gorm:"column:inherits"` // 逗号分隔
+	Condition  string `gorm:"column:condition_name"`
+}
+
+type gormLoader struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewGormLoader 从数据库表加载策略，表结构见 PolicyRecord
+func NewGormLoader(db *gorm.DB, table string) Loader {
+	return &gormLoader{db: db, table: table}
+}
+
+func (g *gormLoader) Load(ctx context.Context) ([]config.RbacPolicy, error) {
+	var records []PolicyRecord
+	if err := g.db.WithContext(ctx).Table(g.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	policies := make([]config.RbacPolicy, 0, len(records))
+	for _, r := range records {
+		policy := config.RbacPolicy{Role: r.Role, Condition: r.Condition}
+		if r.Permission != "" {
+			policy.Permissions = []string{r.Permission}
+		}
+		for _, parent := range strings.Split(r.Inherits, ",") {
+			if parent = strings.TrimSpace(parent); parent != "" {
+				policy.Inherits = append(policy.Inherits, parent)
+			}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
@@ -0,0 +1,284 @@
+package rbac
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyzx-go/common-b2c/config"
+	"github.com/hyzx-go/common-b2c/log"
+)
+
+// Subject 鉴权主体，通常由认证中间件的 claims 构造
+type Subject struct {
+	UserId   string
+	TenantId string
+	Roles    []string
+	Attrs    map[string]interface{}
+}
+
+// Condition 属性条件，policy 配置了 condition 时授权还需要条件成立
+type Condition func(ctx context.Context, sub Subject, permission string) bool
+
+type grant struct {
+	pattern   string
+	condition string
+}
+
+// decision 缓存 roles+permission 的匹配结果，条件依赖请求属性，只缓存需要评估的条件名
+type decision struct {
+	allowed    bool
+	conditions []string
+	expireAt   time.Time
+}
+
+type Options struct {
+	CacheTTL time.Duration
+	// CacheSize 决策缓存上限，超过后整体清空
+	CacheSize int
+	// Reload 定时从 Loader 重新加载策略，0 不重新加载
+	Reload time.Duration
+}
+
+type Option func(*Options)
+
+func SetCacheTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.CacheTTL = ttl
+	}
+}
+
+func SetCacheSize(size int) Option {
+	return func(o *Options) {
+		o.CacheSize = size
+	}
+}
+
+func SetReload(interval time.Duration) Option {
+	return func(o *Options) {
+		o.Reload = interval
+	}
+}
+
+// Enforcer 权限判定
+type Enforcer struct {
+	loader  Loader
+	options Options
+
+	mu         sync.RWMutex
+	grants     map[string][]grant // role -> 展开继承后的授权
+	conditions map[string]Condition
+
+	cacheMu sync.Mutex
+	cache   map[string]decision
+
+	stop chan struct{}
+}
+
+// New 使用静态策略创建 Enforcer
+func New(policies []config.RbacPolicy, opts ...Option) *Enforcer {
+	e, _ := NewWithLoader(context.Background(), StaticLoader(policies), opts...)
+	return e
+}
+
+// NewWithLoader 从 Loader 加载策略，配置了 Reload 时定时刷新
+func NewWithLoader(ctx context.Context, loader Loader, opts ...Option) (*Enforcer, error) {
+	options := Options{CacheTTL: time.Minute, CacheSize: 10000}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	e := &Enforcer{
+		loader:     loader,
+		options:    options,
+		conditions: make(map[string]Condition),
+		cache:      make(map[string]decision),
+		stop:       make(chan struct{}),
+	}
+	if err := e.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if options.Reload > 0 {
+		go e.reloadLoop()
+	}
+	return e, nil
+}
+
+// RegisterCondition 注册属性条件，需在处理请求前注册
+func (e *Enforcer) RegisterCondition(name string, cond Condition) {
+	e.mu.Lock()
+	e.conditions[name] = cond
+	e.mu.Unlock()
+}
+
+// Reload 重新加载策略并清空决策缓存
+func (e *Enforcer) Reload(ctx context.Context) error {
+	policies, err := e.loader.Load(ctx)
+	if err != nil {
+		return err
+	}
+	grants := compile(policies)
+
+	e.mu.Lock()
+	e.grants = grants
+	e.mu.Unlock()
+
+	e.cacheMu.Lock()
+	e.cache = make(map[string]decision)
+	e.cacheMu.Unlock()
+	return nil
+}
+
+// Close 停止定时加载
+func (e *Enforcer) Close() {
+	select {
+	case <-e.stop:
+	default:
+		close(e.stop)
+	}
+}
+
+func (e *Enforcer) reloadLoop() {
+	ticker := time.NewTicker(e.options.Reload)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			if err := e.Reload(context.Background()); err != nil {
+				log.Ctx(nil).Error("rbac reload policies failed", err)
+			}
+		}
+	}
+}
+
+// Allow 判断主体是否拥有权限，并记录决策日志
+func (e *Enforcer) Allow(ctx context.Context, sub Subject, permission string) bool {
+	d := e.decide(sub.Roles, permission)
+
+	allowed := d.allowed
+	if !allowed && len(d.conditions) > 0 {
+		e.mu.RLock()
+		for _, name := range d.conditions {
+			if cond, ok := e.conditions[name]; ok && cond(ctx, sub, permission) {
+				allowed = true
+				break
+			}
+		}
+		e.mu.RUnlock()
+	}
+
+	fields := map[string]interface{}{
+		"user_id":    sub.UserId,
+		"roles":      sub.Roles,
+		"permission": permission,
+		"allowed":    allowed,
+	}
+	if allowed {
+		log.Ctx(ctx).Info("rbac decision", fields)
+	} else {
+		log.Ctx(ctx).Warn("rbac decision", fields)
+	}
+	return allowed
+}
+
+func (e *Enforcer) decide(roles []string, permission string) decision {
+	key := cacheKey(roles, permission)
+	now := time.Now()
+
+	e.cacheMu.Lock()
+	if d, ok := e.cache[key]; ok && now.Before(d.expireAt) {
+		e.cacheMu.Unlock()
+		return d
+	}
+	e.cacheMu.Unlock()
+
+	d := decision{expireAt: now.Add(e.options.CacheTTL)}
+	e.mu.RLock()
+	for _, role := range roles {
+		for _, g := range e.grants[role] {
+			if !Match(g.pattern, permission) {
+				continue
+			}
+			if g.condition == "" {
+				d.allowed = true
+				break
+			}
+			d.conditions = append(d.conditions, g.condition)
+		}
+		if d.allowed {
+			d.conditions = nil
+			break
+		}
+	}
+	e.mu.RUnlock()
+
+	if e.options.CacheTTL > 0 {
+		e.cacheMu.Lock()
+		if len(e.cache) >= e.options.CacheSize {
+			e.cache = make(map[string]decision)
+		}
+		e.cache[key] = d
+		e.cacheMu.Unlock()
+	}
+	return d
+}
+
+func cacheKey(roles []string, permission string) string {
+	sorted := append([]string(nil), roles...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",") + "|" + permission
+}
+
+// compile 展开角色继承，循环继承只展开一次
+func compile(policies []config.RbacPolicy) map[string][]grant {
+	byRole := make(map[string][]config.RbacPolicy)
+	for _, p := range policies {
+		byRole[p.Role] = append(byRole[p.Role], p)
+	}
+
+	grants := make(map[string][]grant, len(byRole))
+	for role := range byRole {
+		visited := make(map[string]bool)
+		var expand func(r string)
+		expand = func(r string) {
+			if visited[r] {
+				return
+			}
+			visited[r] = true
+			for _, p := range byRole[r] {
+				for _, perm := range p.Permissions {
+					grants[role] = append(grants[role], grant{pattern: perm, condition: p.Condition})
+				}
+				for _, parent := range p.Inherits {
+					expand(parent)
+				}
+			}
+		}
+		expand(role)
+	}
+	return grants
+}
+
+// Match 判断权限是否匹配策略，按 ":" 分段，* 匹配单段，末尾的 * 匹配剩余所有段
+func Match(pattern, permission string) bool {
+	if pattern == "*" || pattern == permission {
+		return true
+	}
+	ps := strings.Split(pattern, ":")
+	ts := strings.Split(permission, ":")
+	for i, seg := range ps {
+		if seg == "*" && i == len(ps)-1 {
+			return len(ts) >= len(ps)
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if seg != "*" && seg != ts[i] {
+			return false
+		}
+	}
+	return len(ps) == len(ts)
+}
@@ -0,0 +1,70 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyzx-go/common-b2c/config"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, permission string
+		want                bool
+	}{
+		{"order:write", "order:write", true},
+		{"order:*", "order:write", true},
+		{"order:*", "order:item:write", true},
+		{"order:*:write", "order:item:write", true},
+		{"order:*:write", "order:item:read", false},
+		{"*:read", "user:read", true},
+		{"*", "anything:at:all", true},
+		{"order:write", "order", false},
+		{"order", "order:write", false},
+	}
+	for _, cs := range cases {
+		if got := Match(cs.pattern, cs.permission); got != cs.want {
+			t.Errorf("Match(%q, %q)=%v want %v", cs.pattern, cs.permission, got, cs.want)
+		}
+	}
+}
+
+func TestEnforcer(t *testing.T) {
+	e := New([]config.RbacPolicy{
+		{Role: "viewer", Permissions: []string{"*:read"}},
+		{Role: "editor", Permissions: []string{"order:write"}, Inherits: []string{"viewer"}},
+		{Role: "admin", Permissions: []string{"*"}, Inherits: []string{"editor"}},
+		{Role: "seller", Permissions: []string{"shop:write"}, Condition: "same_tenant"},
+		// 循环继承不应死循环
+		{Role: "a", Inherits: []string{"b"}},
+		{Role: "b", Inherits: []string{"a"}, Permissions: []string{"b:read"}},
+	})
+	e.RegisterCondition("same_tenant", func(ctx context.Context, sub Subject, permission string) bool {
+		return sub.TenantId == "t1"
+	})
+	ctx := context.Background()
+
+	cases := []struct {
+		sub        Subject
+		permission string
+		want       bool
+	}{
+		{Subject{Roles: []string{"viewer"}}, "order:read", true},
+		{Subject{Roles: []string{"viewer"}}, "order:write", false},
+		{Subject{Roles: []string{"editor"}}, "order:write", true},
+		{Subject{Roles: []string{"editor"}}, "user:read", true},
+		{Subject{Roles: []string{"admin"}}, "system:config:write", true},
+		{Subject{Roles: []string{"seller"}, TenantId: "t1"}, "shop:write", true},
+		{Subject{Roles: []string{"seller"}, TenantId: "t2"}, "shop:write", false},
+		{Subject{Roles: []string{"a"}}, "b:read", true},
+		{Subject{}, "order:read", false},
+	}
+	// 跑两遍，第二遍命中决策缓存，结果应一致
+	for i := 0; i < 2; i++ {
+		for _, cs := range cases {
+			if got := e.Allow(ctx, cs.sub, cs.permission); got != cs.want {
+				t.Errorf("round %d %v %s: %v want %v", i, cs.sub.Roles, cs.permission, got, cs.want)
+			}
+		}
+	}
+}
@@ -8,12 +8,14 @@ type ErrorCode int
 // 通用错误码
 const (
 	// 通用成功与客户端错误码
-	Success      ErrorCode = 0   // 成功
-	BadRequest   ErrorCode = 400 // 请求参数错误
-	Unauthorized ErrorCode = 401 // 未授权
-	Forbidden    ErrorCode = 403 // 禁止访问
-	NotFound     ErrorCode = 404 // 资源未找到
-	Conflict     ErrorCode = 409 // 资源冲突
+	Success         ErrorCode = 0   // 成功
+	BadRequest      ErrorCode = 400 // 请求参数错误
+	Unauthorized    ErrorCode = 401 // 未授权
+	Forbidden       ErrorCode = 403 // 禁止访问
+	NotFound        ErrorCode = 404 // 资源未找到
+	Conflict        ErrorCode = 409 // 资源冲突
+	PayloadTooLarge ErrorCode = 413 // 请求体过大
+	TooManyRequests ErrorCode = 429 // 请求过于频繁
 
 	// 服务端错误码
 	InternalError      ErrorCode = 500 // 服务器内部错误
@@ -82,6 +84,8 @@ var langMap = map[Lang]map[ErrorCode]string{
 		Forbidden:           "Forbidden",
 		NotFound:            "Resource not found",
 		Conflict:            "Resource conflict",
+		PayloadTooLarge:     "Request body too large",
+		TooManyRequests:     "Too many requests",
 		InternalError:       "Internal server error",
 		ServiceUnavailable:  "Service unavailable",
 		Timeout:             "Request timeout",
@@ -99,6 +103,8 @@ var langMap = map[Lang]map[ErrorCode]string{
 		Forbidden:           "禁止访问",
 		NotFound:            "资源未找到",
 		Conflict:            "资源冲突",
+		PayloadTooLarge:     "请求体过大",
+		TooManyRequests:     "请求过于频繁",
 		InternalError:       "服务器内部错误",
 		ServiceUnavailable:  "服务不可用",
 		Timeout:             "请求超时",
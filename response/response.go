@@ -2,6 +2,7 @@ package response
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/errclass"
 	"github.com/hyzx-go/common-b2c/utils"
 	"io"
 	"net/http"
@@ -52,6 +53,51 @@ func Fail(errResp *ErrResp, c *gin.Context) {
 	})
 }
 
+// FailWithStatus 以指定的 HTTP 状态码返回错误并终止后续处理，用于中间件拦截
+func FailWithStatus(httpStatus int, code ErrorCode, data interface{}, c *gin.Context) {
+	if c.IsAborted() {
+		return
+	}
+	module, detailCode := ParseErrorCode(code)
+
+	c.AbortWithStatusJSON(httpStatus, Response{
+		TraceId:    utils.GetTraceId(c),
+		Code:       code,
+		Module:     module.String(),
+		DetailCode: detailCode,
+		Message:    GetErrorMessage(code, Lang(c.GetHeader("Accept-Language"))),
+		Data:       data,
+	})
+}
+
+// FailWithError 按 errclass 分类返回错误码，未分类的错误返回 InternalError；错误本身不返回给客户端，
+// 通过 c.Error 记录，由请求日志与链路追踪输出
+func FailWithError(err error, c *gin.Context) {
+	if c.IsAborted() {
+		return
+	}
+	_ = c.Error(err)
+	code := ErrorCodeOf(err)
+	module, detailCode := ParseErrorCode(code)
+
+	c.JSON(http.StatusOK, Response{
+		TraceId:    utils.GetTraceId(c),
+		Code:       code,
+		Module:     module.String(),
+		DetailCode: detailCode,
+		Message:    GetErrorMessage(code, Lang(c.GetHeader("Accept-Language"))),
+	})
+	c.Abort()
+}
+
+// ErrorCodeOf 返回错误在 errclass 中登记的错误码，未分类或未指定错误码时返回 InternalError
+func ErrorCodeOf(err error) ErrorCode {
+	if class, ok := errclass.Classify(err); ok && class.Code != 0 {
+		return ErrorCode(class.Code)
+	}
+	return InternalError
+}
+
 // 成功响应
 func Ok(data interface{}, c *gin.Context) {
 	c.JSON(http.StatusOK, Response{
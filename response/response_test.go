@@ -0,0 +1,60 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/hyzx-go/common-b2c/errclass"
+	"gorm.io/gorm"
+)
+
+// errclass 不依赖 response，默认分类的错误码单独定义，这里保证与 ErrorCode 一致
+func TestErrclassCodes(t *testing.T) {
+	cases := []struct {
+		err  error
+		code ErrorCode
+	}{
+		{gorm.ErrDuplicatedKey, ResourceExists},
+		{gorm.ErrForeignKeyViolated, Conflict},
+		{gorm.ErrInvalidDB, DatabaseError},
+		{mysql.ErrInvalidConn, ServiceUnavailable},
+	}
+	for _, c := range cases {
+		class, ok := errclass.Classify(c.err)
+		if !ok || ErrorCode(class.Code) != c.code {
+			t.Errorf("errclass %s code %d, want %d", class.Name, class.Code, c.code)
+		}
+	}
+}
+
+func TestFailWithError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		err  error
+		code ErrorCode
+	}{
+		{fmt.Errorf("create user: %w", gorm.ErrDuplicatedKey), ResourceExists},
+		{gorm.ErrForeignKeyViolated, Conflict},
+		{errors.New("boom"), InternalError},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("POST", "/users", nil)
+
+		FailWithError(c.err, ctx)
+
+		var resp Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != c.code || resp.Message != GetErrorMessage(c.code, En) || !ctx.IsAborted() || len(ctx.Errors) != 1 {
+			t.Errorf("FailWithError(%v) = %+v", c.err, resp)
+		}
+	}
+}
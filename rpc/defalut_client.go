@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
 	"github.com/hyzx-go/common-b2c/log"
 	"github.com/hyzx-go/common-b2c/utils"
 	"io/ioutil"
@@ -16,12 +17,14 @@ import (
 )
 
 type httpClient struct {
-	cli *http.Client
+	cli   *http.Client
+	hooks []RequestHook
 }
 
 // NewHttpClient Create a Http, Cannot support golang init()
-func NewHttpClient(cli *http.Client) Http {
-	return &httpClient{cli: cli}
+// hooks run in order before each request is sent, e.g. to sign it
+func NewHttpClient(cli *http.Client, hooks ...RequestHook) Http {
+	return &httpClient{cli: cli, hooks: hooks}
 }
 
 // GetClient get origin http client
@@ -158,7 +161,19 @@ func (h *httpClient) Call(ctx context.Context, reqDTO HttpReqDTO, timeout time.D
 
 	buildHeaders(httpReqDTO, reqDTO.Headers)
 
-	httpRes, err := h.cli.Do(httpReqDTO.WithContext(ctx))
+	// forward the request id so the callee logs with the same trace-id
+	if requestId := ctxkeys.TraceId(ctx); requestId != "" && httpReqDTO.Header.Get(RequestIdHeader) == "" {
+		httpReqDTO.Header.Set(RequestIdHeader, requestId)
+	}
+
+	httpReqDTO = httpReqDTO.WithContext(ctx)
+	for _, hook := range h.hooks {
+		if err := hook(httpReqDTO); err != nil {
+			return 0, data, fmt.Errorf("HttpRequest hook error, %w", err)
+		}
+	}
+
+	httpRes, err := h.cli.Do(httpReqDTO)
 	if err != nil {
 		return http.StatusInternalServerError, data, fmt.Errorf("sync request error, %w", err)
 	}
@@ -16,6 +16,7 @@ type (
 
 const (
 	Authorization               = "Authorization"
+	RequestIdHeader             = "X-Request-Id"
 	FormContentType ContentType = "application/x-www-form-urlencoded; charset=utf-8"
 	JsonContentType ContentType = "application/json; charset=utf-8"
 )
@@ -28,6 +29,9 @@ const (
 	Patch    RequestType = "PATCH"
 )
 
+// RequestHook is invoked with the outgoing request before it is sent
+type RequestHook func(req *http.Request) error
+
 type HttpReqDTO struct {
 	*Builder
 }
@@ -0,0 +1,75 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NonceStore 记录已使用的 nonce，Use 首次登记返回 true
+type NonceStore interface {
+	Use(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+type memoryNonceStore struct {
+	mu    sync.Mutex
+	items map[string]time.Time
+	last  time.Time
+}
+
+// NewMemoryNonceStore 进程内 nonce 记录，多实例部署时使用 NewRedisNonceStore
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{items: make(map[string]time.Time)}
+}
+
+func (m *memoryNonceStore) Use(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 每分钟清理一次过期 nonce
+	if now.Sub(m.last) > time.Minute {
+		for k, expireAt := range m.items {
+			if now.After(expireAt) {
+				delete(m.items, k)
+			}
+		}
+		m.last = now
+	}
+
+	if expireAt, ok := m.items[key]; ok && now.Before(expireAt) {
+		return false, nil
+	}
+	m.items[key] = now.Add(ttl)
+	return true, nil
+}
+
+type redisNonceStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisNonceStore 基于 SET NX 的 nonce 记录，key 前缀 sign:nonce:
+func NewRedisNonceStore(pool *redis.Pool) NonceStore {
+	return &redisNonceStore{pool: pool, prefix: "sign:nonce:"}
+}
+
+func (r *redisNonceStore) Use(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	_, err = redis.String(redis.DoContext(conn, ctx, "SET", r.prefix+key, 1, "NX", "PX", ttl.Milliseconds()))
+	if errors.Is(err, redis.ErrNil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
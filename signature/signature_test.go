@@ -0,0 +1,52 @@
+package signature
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCanonicalSortsQuery(t *testing.T) {
+	a := Canonical("post", "/order", url.Values{"b": {"2", "1"}, "a": {"x y"}}, []byte("{}"), "1700000000", "n1")
+	b := Canonical("POST", "/order", url.Values{"a": {"x y"}, "b": {"1", "2"}}, []byte("{}"), "1700000000", "n1")
+	if a != b {
+		t.Fatalf("canonical should not depend on order:\n%s\n%s", a, b)
+	}
+	if !strings.Contains(a, "\na=x+y&b=1&b=2\n") {
+		t.Fatalf("unexpected query part: %q", a)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	signer := NewSigner("app1", "secret")
+	verifier := NewVerifier(map[string]string{"app1": "secret"}, time.Minute, NewMemoryNonceStore())
+	ctx := context.Background()
+
+	req := httptest.NewRequest(http.MethodPost, "/order?id=1", strings.NewReader(`{"a":1}`))
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, req, []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, req, []byte(`{"a":1}`)); err != ErrReplay {
+		t.Fatalf("replay: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/order?id=1", strings.NewReader(`{"a":1}`))
+	_ = signer.Sign(req)
+	if _, err := verifier.Verify(ctx, req, []byte(`{"a":2}`)); err != ErrInvalid {
+		t.Fatalf("tampered body: %v", err)
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(-2 * time.Minute) }
+	req = httptest.NewRequest(http.MethodGet, "/order", nil)
+	_ = signer.Sign(req)
+	if _, err := verifier.Verify(ctx, req, nil); err != ErrExpired {
+		t.Fatalf("expired: %v", err)
+	}
+}
@@ -1,14 +1,21 @@
 package common_b2c
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/hyzx-go/common-b2c/config"
 	innerLog "github.com/hyzx-go/common-b2c/log"
 	middlewares "github.com/hyzx-go/common-b2c/middleware"
 	"github.com/hyzx-go/common-b2c/pool"
+	"github.com/hyzx-go/common-b2c/tracing"
 	"github.com/hyzx-go/common-b2c/utils"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -40,9 +47,28 @@ func (s *Service) Run(routerModules []func(r *gin.RouterGroup)) {
 
 	// 创建 Gin 实例
 	r := gin.New()
+	// gin.Context 作为 context.Context 传递时可读取到请求上下文中的 span 等数据
+	r.ContextWithFallback = true
+
+	// 链路追踪需最先执行，后续中间件与日志使用同一个 trace id
+	if tracing.Enabled() {
+		r.Use(tracing.GinMiddleware())
+	}
+	r.Use(middlewares.RequestId())
+
+	// 跨域需挂在引擎上，未注册 OPTIONS 路由的预检请求也能命中
+	if _, err := s.parser.GetCorsConf(); err == nil {
+		r.Use(middlewares.Cors())
+	}
+
+	// 日志级别管理接口，log.admin_token 为空时不注册
+	if logConf, err := s.parser.GetLogConf(); err == nil {
+		innerLog.RegisterAdmin(r, logConf.AdminPath, logConf.AdminToken)
+	}
 
 	// 注册模块路由
 	group := r.Group("", middlewares.RateLimitMiddleware(),
+		middlewares.BodyLimitMiddleware(), middlewares.TimeoutMiddleware(),
 		innerLog.RequestLogger(), innerLog.GinRecovery())
 	for _, module := range routerModules {
 		module(group)
@@ -53,12 +79,39 @@ func (s *Service) Run(routerModules []func(r *gin.RouterGroup)) {
 		log.Fatalf("Failed to start server get sys conf: %v", err)
 	}
 	// 启动服务
-	log.Printf("Starting server on port %s...", sysConf.ServePort)
-	if err := r.Run(":" + sysConf.ServePort); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":" + sysConf.ServePort, Handler: r}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on port %s...", sysConf.ServePort)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	// 收到退出信号后停止接收新请求，等待处理中的请求结束
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			innerLog.CloseSinks()
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	case sig := <-quit:
+		log.Printf("Received signal %s, shutting down server...", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Server shutdown: %v", err)
+		}
+		cancel()
 	}
+
+	// 文件日志默认异步写入，退出前写完队列中的日志
+	innerLog.CloseSinks()
 }
 
+// shutdownTimeout 优雅退出时等待处理中请求的最长时间
+const shutdownTimeout = 10 * time.Second
+
 type Service struct {
 	startTime time.Time
 	parser    config.Parser
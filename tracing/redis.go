@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type redisConn struct {
+	redis.Conn
+	instance string
+}
+
+// WrapRedisConn 包装 redis 连接，redis.DoContext 与 Script.DoContext 调用的命令会创建 span。
+// redigo 的 Do 不携带 context，无法关联请求链路，不创建 span；框架内的 lock、cache、幂等、限流与 nonce 均使用 DoContext。
+func WrapRedisConn(conn redis.Conn, instance string) redis.Conn {
+	return &redisConn{Conn: conn, instance: instance}
+}
+
+func (c *redisConn) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	cwc, ok := c.Conn.(redis.ConnWithContext)
+	if !ok {
+		return nil, errors.New("redis: connection does not support ConnWithContext")
+	}
+	// 空命令为 flush pipeline，不记录
+	if cmd == "" || !trace.SpanContextFromContext(ctx).IsValid() {
+		return cwc.DoContext(ctx, cmd, args...)
+	}
+
+	ctx, span := Tracer().Start(ctx, "redis."+cmd,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.instance", c.instance),
+			attribute.String("db.operation", cmd),
+			attribute.Int("db.redis.args", len(args)),
+		),
+	)
+	defer span.End()
+
+	reply, err := cwc.DoContext(ctx, cmd, args...)
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return reply, err
+}
+
+func (c *redisConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	cwc, ok := c.Conn.(redis.ConnWithContext)
+	if !ok {
+		return nil, errors.New("redis: connection does not support ConnWithContext")
+	}
+	return cwc.ReceiveContext(ctx)
+}
+
+func (c *redisConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *redisConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterNone   = "none"
+
+	instrumentationName = "github.com/hyzx-go/common-b2c/tracing"
+)
+
+// Options 链路追踪配置
+type Options struct {
+	ServiceName string
+	Version     string
+	Env         string
+	// Exporter otlp/stdout/file/none
+	Exporter string
+	// Endpoint OTLP HTTP 地址，如 otel-collector:4318
+	Endpoint string
+	Insecure bool
+	Headers  map[string]string
+	// File Exporter 为 file 时的输出文件
+	File string
+	// SampleRatio 采样比例，上游已采样的请求始终跟随上游
+	SampleRatio float64
+}
+
+var (
+	enabled  atomic.Bool
+	provider *sdktrace.TracerProvider
+	closer   io.Closer
+)
+
+// Init 安装全局 TracerProvider 与 W3C traceparent 传播器
+func Init(opts Options) error {
+	if opts.Exporter == "" || opts.Exporter == ExporterNone {
+		return nil
+	}
+
+	exporter, err := newExporter(opts)
+	if err != nil {
+		return err
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", opts.ServiceName),
+		attribute.String("service.version", opts.Version),
+		attribute.String("deployment.environment", opts.Env),
+	)
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled.Store(true)
+	return nil
+}
+
+func newExporter(opts Options) (sdktrace.SpanExporter, error) {
+	switch opts.Exporter {
+	case ExporterOTLP:
+		clientOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+		if len(opts.Headers) > 0 {
+			clientOpts = append(clientOpts, otlptracehttp.WithHeaders(opts.Headers))
+		}
+		return otlptracehttp.New(context.Background(), clientOpts...)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, errors.New("tracing file exporter requires file")
+		}
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		closer = f
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	}
+	return nil, fmt.Errorf("tracing exporter not support:%s", opts.Exporter)
+}
+
+// Enabled 是否已启用链路追踪
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Shutdown 导出剩余 span 并关闭
+func Shutdown(ctx context.Context) error {
+	if !enabled.CompareAndSwap(true, false) {
+		return nil
+	}
+	err := provider.Shutdown(ctx)
+	if closer != nil {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Tracer 框架内使用的 tracer，未启用时为 noop
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceId 返回 ctx 中 span 的 trace id，没有时返回空串
+func TraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGinAndTransportPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// 下游服务回显收到的 traceparent
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("traceparent")))
+	}))
+	defer downstream.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(GinMiddleware())
+	r.GET("/order/:id", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c, http.MethodGet, downstream.URL, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer res.Body.Close()
+		traceparent, _ := io.ReadAll(res.Body)
+		c.String(http.StatusOK, ctxkeys.TraceId(c)+" "+string(traceparent))
+	})
+
+	upstreamTraceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+	req.Header.Set("traceparent", "00-"+upstreamTraceId+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var ginTraceId, traceparent string
+	if _, err := fmt.Sscan(w.Body.String(), &ginTraceId, &traceparent); err != nil {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if ginTraceId != upstreamTraceId {
+		t.Fatalf("gin trace-id %s want upstream %s", ginTraceId, upstreamTraceId)
+	}
+	if len(traceparent) != 55 || traceparent[3:35] != upstreamTraceId {
+		t.Fatalf("downstream traceparent %q", traceparent)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans %d", len(spans))
+	}
+	clientSpan, server := spans[0], spans[1]
+	if server.Name() != "GET /order/:id" || clientSpan.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("unexpected spans: %s parent=%s, %s", clientSpan.Name(), clientSpan.Parent().SpanID(), server.Name())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/hyzx-go/common-b2c/ctxkeys"
 	"log"
 	"math/rand"
 	"runtime/debug"
@@ -72,12 +73,12 @@ type MapStr map[string]interface{}
 func GetTraceId(ctx ...*gin.Context) string {
 	// 如果传入了 gin.Context，则尝试从上下文中获取或设置 trace-id
 	if len(ctx) > 0 && ctx[0] != nil {
-		if traceId := ctx[0].GetString("trace-id"); traceId != "" {
+		if traceId := ctxkeys.TraceId(ctx[0]); traceId != "" {
 			return traceId
 		}
 		// 如果 trace-id 不存在，则生成一个新的 trace-id 并存入上下文
 		traceId := strings.ReplaceAll(uuid.New().String(), "-", "")
-		ctx[0].Set("trace-id", traceId)
+		ctxkeys.SetTraceId(ctx[0], traceId)
 		return traceId
 	}
 